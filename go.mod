@@ -3,8 +3,11 @@ module github.com/kubermatic-labs/gman
 go 1.20
 
 require (
+	github.com/GehirnInc/crypt v0.0.0-20230320061759-8cc1b52080c5
 	github.com/sethvargo/go-password v0.2.0
 	golang.org/x/oauth2 v0.11.0
+	golang.org/x/sync v0.3.0
+	golang.org/x/time v0.3.0
 	google.golang.org/api v0.138.0
 	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/apimachinery v0.28.0
@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This program runs the API emulator on a fixed address, so that GMan
+// can be tried out without a real GSuite organization:
+//
+//	go run ./hack/emulator -listen 127.0.0.1:8080
+//	gman -api-endpoint http://127.0.0.1:8080/ -orgunits-config ... -confirm
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/emulator"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+func main() {
+	var (
+		listen       string
+		organization string
+		pageSize     int
+	)
+
+	flag.StringVar(&listen, "listen", "127.0.0.1:8080", "address to listen on")
+	flag.StringVar(&organization, "organization", "example", "name of the emulated organization")
+	flag.IntVar(&pageSize, "page-size", emulator.DefaultPageSize, "maximum number of items per page in list responses")
+	flag.Parse()
+
+	handler := emulator.NewHandler(fake.NewWorkspace(organization, config.AllLicenses))
+	handler.PageSize = pageSize
+
+	log.Printf("► Emulating organization %q on http://%s/…", organization, listen)
+
+	if err := http.ListenAndServe(listen, handler); err != nil {
+		log.Fatalf("⚠ Failed to serve: %v.", err)
+	}
+}
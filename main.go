@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	directoryv1 "google.golang.org/api/admin/directory/v1"
@@ -52,13 +53,27 @@ type options struct {
 	confirm               bool
 	validateAction        bool
 	exportAction          bool
+	expandGroupsAction    bool
+	expirationsAction     bool
+	expiryWarningDays     int
 	licensesAction        bool
 	licensesYAML          bool
+	planOutFile           string
+	applyPlanFile         string
 	clientSecretFile      string
 	impersonatedUserEmail string
+	apiEndpoint           string
 	insecurePasswords     bool
 	throttleRequests      time.Duration
+	retryBudget           int
+	concurrency           int
+	maxDeletions          int
+	maxDeletionPercent    int
 	licenses              []config.License
+	onlyUsers             string
+	onlyGroups            string
+	onlyOrgUnitSubtrees   string
+	selection             *config.Selection
 }
 
 func main() {
@@ -73,14 +88,27 @@ func main() {
 	flag.StringVar(&opt.licensesConfigFile, "licenses-config", "", "(optional) instead of using the inbuilt license list, this is a config.yaml that contains the relevant licenses")
 	flag.StringVar(&opt.clientSecretFile, "private-key", "", "path to the Service Account secret file (.json) coontaining Keys used for authorization")
 	flag.StringVar(&opt.impersonatedUserEmail, "impersonated-email", "", "Admin email used to impersonate Service Account")
+	flag.StringVar(&opt.apiEndpoint, "api-endpoint", "", "(optional) base URL of an API server to use instead of googleapis.com, e.g. a local emulator (the private key is optional in this case)")
 	flag.BoolVar(&opt.versionAction, "version", false, "show the GMan version and exit")
 	flag.BoolVar(&opt.validateAction, "validate", false, "validate the given configuration and then exit")
 	flag.BoolVar(&opt.exportAction, "export", false, "export the state and update the config files (-[user|groups|orgunits]-config flags)")
+	flag.BoolVar(&opt.expandGroupsAction, "expand-groups", false, "print the effective members of all configured groups, including those of nested groups, and then exit")
+	flag.BoolVar(&opt.expirationsAction, "expirations", false, "print the group memberships that have expired or expire within -expiry-warning-days and then exit")
+	flag.IntVar(&opt.expiryWarningDays, "expiry-warning-days", 30, "warn about group memberships that expire within this many days")
 	flag.BoolVar(&opt.licensesAction, "licenses", false, "print the builtin licenses and then exit")
 	flag.BoolVar(&opt.licensesYAML, "licenses-yaml", false, "print the builtin licenses as YAML (use together with -licenses)")
 	flag.BoolVar(&opt.confirm, "confirm", false, "must be set to actually perform any changes")
+	flag.IntVar(&opt.maxDeletions, "max-deletions", 0, "abort if more than this many org units, users or groups would be deleted (applies to each resource type separately, 0 means unlimited)")
+	flag.IntVar(&opt.maxDeletionPercent, "max-deletion-percent", 50, "abort if more than this percentage of the live org units, users or groups would be deleted (applies to each resource type separately, 0 means unlimited)")
+	flag.StringVar(&opt.planOutFile, "plan-out", "", "(optional) write the planned changes as JSON to this file, e.g. for later use with -apply-plan")
+	flag.StringVar(&opt.applyPlanFile, "apply-plan", "", "(optional) apply the changes from a previously saved plan instead of computing a new one (requires -confirm)")
 	flag.BoolVar(&opt.insecurePasswords, "insecure-passwords", false, "allow configuring static passwords for users")
-	flag.DurationVar(&opt.throttleRequests, "throttle-requests", 500*time.Millisecond, "the delay between Enterprise Licensing API requests")
+	flag.DurationVar(&opt.throttleRequests, "throttle-requests", 500*time.Millisecond, "the delay between requests to each of the Directory, Enterprise Licensing, Groups Settings and Data Transfer APIs")
+	flag.IntVar(&opt.concurrency, "concurrency", 4, "the number of groups to fetch members and settings for in parallel")
+	flag.IntVar(&opt.retryBudget, "retry-budget", 50, "the total number of retries for rate-limited or failed API requests before giving up")
+	flag.StringVar(&opt.onlyUsers, "only-users", "", "(optional) comma-separated emails or glob patterns; only synchronize these users")
+	flag.StringVar(&opt.onlyGroups, "only-groups", "", "(optional) comma-separated emails or glob patterns; only synchronize these groups")
+	flag.StringVar(&opt.onlyOrgUnitSubtrees, "only-orgunit-subtree", "", "(optional) comma-separated org unit paths; only synchronize these org units, their children and the users in them")
 	flag.Parse()
 
 	if opt.versionAction {
@@ -93,6 +121,28 @@ func main() {
 		return
 	}
 
+	if opt.applyPlanFile != "" && opt.exportAction {
+		log.Fatal("⚠ -apply-plan and -export cannot be used together.")
+	}
+
+	if opt.expandGroupsAction && (opt.exportAction || opt.groupsConfigFile == "") {
+		log.Fatal("⚠ -expand-groups requires -groups-config and cannot be used with -export.")
+	}
+
+	if opt.expirationsAction && (opt.exportAction || opt.groupsConfigFile == "") {
+		log.Fatal("⚠ -expirations requires -groups-config and cannot be used with -export.")
+	}
+
+	opt.selection = &config.Selection{
+		Users:        splitList(opt.onlyUsers),
+		Groups:       splitList(opt.onlyGroups),
+		OrgUnitTrees: splitList(opt.onlyOrgUnitSubtrees),
+	}
+
+	if !opt.selection.Empty() && (opt.exportAction || opt.applyPlanFile != "") {
+		log.Fatal("⚠ -only-users, -only-groups and -only-orgunit-subtree cannot be used with -export or -apply-plan.")
+	}
+
 	// open the files
 	if opt.usersConfigFile != "" {
 		opt.usersConfig, err = config.LoadFromFile(opt.usersConfigFile)
@@ -135,6 +185,16 @@ func main() {
 		if opt.validateAction {
 			return
 		}
+
+		if opt.expandGroupsAction {
+			expandGroupsAction(opt.groupsConfig)
+			return
+		}
+
+		if opt.expirationsAction {
+			expirationsAction(opt.groupsConfig, opt.expiryWarningDays)
+			return
+		}
 	}
 
 	orgName := opt.groupsConfig.Organization
@@ -149,24 +209,35 @@ func main() {
 	readonly := opt.exportAction || !opt.confirm
 	scopes := getScopes(readonly)
 
-	directorySrv, err := glib.NewDirectoryService(ctx, orgName, opt.clientSecretFile, opt.impersonatedUserEmail, opt.throttleRequests, scopes...)
+	// the customer ID is only needed to add CUSTOMER members to groups
+	if !readonly && hasCustomerMembers(opt.groupsConfig) {
+		scopes = append(scopes, directoryv1.AdminDirectoryCustomerReadonlyScope)
+	}
+	budget := glib.NewRetryBudget(opt.retryBudget)
+
+	directorySrv, err := glib.NewDirectoryService(ctx, orgName, opt.clientSecretFile, opt.impersonatedUserEmail, opt.apiEndpoint, opt.throttleRequests, budget, scopes...)
 	if err != nil {
 		log.Fatalf("⚠ Failed to create GSuite Directory API client: %v", err)
 	}
 
-	licensingSrv, err := glib.NewLicensingService(ctx, orgName, opt.clientSecretFile, opt.impersonatedUserEmail, opt.throttleRequests, opt.licenses)
+	licensingSrv, err := glib.NewLicensingService(ctx, orgName, opt.clientSecretFile, opt.impersonatedUserEmail, opt.apiEndpoint, opt.throttleRequests, budget, opt.licenses)
 	if err != nil {
 		log.Fatalf("⚠ Failed to create GSuite Licensing API client: %v", err)
 	}
 
-	groupsSettingsSrv, err := glib.NewGroupsSettingsService(ctx, opt.clientSecretFile, opt.impersonatedUserEmail, opt.throttleRequests)
+	groupsSettingsSrv, err := glib.NewGroupsSettingsService(ctx, opt.clientSecretFile, opt.impersonatedUserEmail, opt.apiEndpoint, opt.throttleRequests, budget)
 	if err != nil {
 		log.Fatalf("⚠ Failed to create GSuite GroupsSettings API client: %v", err)
 	}
 
 	// begin actual work
+	if opt.applyPlanFile != "" {
+		applyPlanAction(ctx, &opt, orgName, directorySrv, licensingSrv, groupsSettingsSrv, budget)
+		return
+	}
+
 	log.Println("► Fetching license status…")
-	opt.licenseStatus, err = licensingSrv.GetLicenseStatus(ctx)
+	opt.licenseStatus, err = glib.GetLicenseStatus(ctx, licensingSrv)
 	if err != nil {
 		log.Fatalf("⚠ Failed to fetch: %v.", err)
 	}
@@ -174,7 +245,7 @@ func main() {
 	if opt.exportAction {
 		exportAction(ctx, &opt, directorySrv, licensingSrv, groupsSettingsSrv)
 	} else {
-		syncAction(ctx, &opt, directorySrv, licensingSrv, groupsSettingsSrv)
+		syncAction(ctx, &opt, directorySrv, licensingSrv, groupsSettingsSrv, budget)
 	}
 }
 
@@ -197,61 +268,243 @@ func licenseAction(asYAML bool) {
 	}
 }
 
+func expandGroupsAction(cfg *config.Config) {
+	for _, group := range cfg.Groups {
+		members := cfg.EffectiveMembers(group.Email)
+
+		fmt.Printf("%s (%d effective members)\n", group.Email, len(members))
+		for _, member := range members {
+			details := []string{}
+			if member.Type != "" && member.Type != config.MemberTypeUser {
+				details = append(details, member.Type)
+			}
+
+			if len(member.Via) > 0 {
+				details = append(details, "via "+strings.Join(member.Via, " → "))
+			}
+
+			name := member.Email
+			if name == "" {
+				name = "all users of the organization"
+			}
+
+			if len(details) > 0 {
+				fmt.Printf("  - %s (%s)\n", name, strings.Join(details, ", "))
+			} else {
+				fmt.Printf("  - %s\n", name)
+			}
+		}
+	}
+}
+
+func expirationsAction(cfg *config.Config, days int) {
+	now := time.Now()
+	expirations := cfg.Expirations(now.AddDate(0, 0, days))
+
+	today, _ := time.Parse(config.MemberDateLayout, now.Format(config.MemberDateLayout))
+
+	fmt.Printf("%d memberships have expired or expire within %d days\n", len(expirations), days)
+	for _, expiration := range expirations {
+		var status string
+
+		switch left := int(expiration.Expires.Sub(today).Hours() / 24); {
+		case expiration.Expired(now):
+			status = "expired"
+		case left == 0:
+			status = "last day"
+		case left == 1:
+			status = "1 day left"
+		default:
+			status = fmt.Sprintf("%d days left", left)
+		}
+
+		fmt.Printf("  - %s: %s in %s (%s)\n", expiration.Member.Expires, expiration.Member.Email, expiration.Group, status)
+	}
+}
+
 func syncAction(
 	ctx context.Context,
 	opt *options,
-	directorySrv *glib.DirectoryService,
-	licensingSrv *glib.LicensingService,
-	groupsSettingsSrv *glib.GroupsSettingsService,
+	directorySrv glib.DirectoryClient,
+	licensingSrv glib.LicensingClient,
+	groupsSettingsSrv glib.GroupsSettingsClient,
+	budget *glib.RetryBudget,
 ) {
-	orgUnitChanges, err := sync.SyncOrgUnits(ctx, directorySrv, opt.orgUnitsConfig, opt.confirm)
-	if err != nil {
-		log.Fatalf("⚠ Failed to sync: %v.", err)
+	plan := sync.NewPlan(opt.groupsConfig.Organization)
+
+	// never delete the account GMan is acting as
+	if opt.usersConfig != nil {
+		opt.usersConfig.Protected.Users = protectedResources(opt).Users
 	}
 
-	err = sync.SyncSchema(ctx, directorySrv, opt.confirm)
-	if err != nil {
-		log.Fatalf("⚠ Failed to sync: %v.", err)
+	if !opt.selection.Empty() {
+		log.Println("☞ Only synchronizing the selected resources.")
 	}
 
-	userChanges := false
-	if opt.usersConfig != nil {
-		userChanges, err = sync.SyncUsers(ctx, directorySrv, licensingSrv, opt.usersConfig, opt.licenseStatus, opt.insecurePasswords, opt.confirm)
-		if err != nil {
+	if opt.selection.SelectsOrgUnits() {
+		if err := sync.PlanOrgUnits(ctx, plan, directorySrv, opt.orgUnitsConfig, opt.selection); err != nil {
 			log.Fatalf("⚠ Failed to sync: %v.", err)
 		}
-	} else {
-		log.Println("⚠ No user configuration provided, not synchronizing users.")
 	}
 
-	groupChanges := false
-	if opt.groupsConfig != nil {
-		groupChanges, err = sync.SyncGroups(ctx, directorySrv, groupsSettingsSrv, opt.groupsConfig, opt.confirm)
-		if err != nil {
+	if opt.selection.SelectsUsers() {
+		if err := sync.PlanSchema(ctx, plan, directorySrv); err != nil {
 			log.Fatalf("⚠ Failed to sync: %v.", err)
 		}
-	} else {
+	}
+
+	switch {
+	case !opt.selection.SelectsUsers():
+		// users are not part of the selection
+	case opt.usersConfig == nil:
+		log.Println("⚠ No user configuration provided, not synchronizing users.")
+	default:
+		if err := sync.PlanUsers(ctx, plan, directorySrv, opt.usersConfig, opt.selection, opt.licenseStatus, opt.insecurePasswords); err != nil {
+			log.Fatalf("⚠ Failed to sync: %v.", err)
+		}
+	}
+
+	switch {
+	case !opt.selection.SelectsGroups():
+		// groups are not part of the selection
+	case opt.groupsConfig == nil:
 		log.Println("⚠ No group configuration provided, not synchronizing groups.")
+	default:
+		if err := sync.PlanGroups(ctx, plan, directorySrv, groupsSettingsSrv, opt.groupsConfig, opt.selection, opt.concurrency); err != nil {
+			log.Fatalf("⚠ Failed to sync: %v.", err)
+		}
 	}
 
-	if opt.confirm {
-		log.Println("✓ Organization successfully synchronized.")
-	} else if orgUnitChanges || userChanges || groupChanges {
-		log.Println("⚠ Run again with -confirm to apply the changes above.")
-	} else {
+	if err := sync.CheckDeletions(plan, opt.maxDeletions, opt.maxDeletionPercent); err != nil {
+		log.Fatalf("⚠ Aborting, too many deletions: %v.", err)
+	}
+
+	if opt.planOutFile != "" {
+		if err := sync.SavePlanToFile(plan, opt.planOutFile); err != nil {
+			log.Fatalf("⚠ Failed to write plan to %q: %v.", opt.planOutFile, err)
+		}
+
+		log.Printf("✓ Plan written to %q.", opt.planOutFile)
+	}
+
+	if plan.Empty() {
 		log.Println("✓ No changes necessary, organization is in sync.")
+		return
 	}
+
+	if !opt.confirm {
+		log.Println("⚠ Run again with -confirm to apply the changes above.")
+		return
+	}
+
+	dataTransferSrv := dataTransferService(ctx, opt, plan, budget)
+
+	log.Println("► Applying changes…")
+	if err := sync.ApplyPlan(ctx, plan, directorySrv, licensingSrv, groupsSettingsSrv, dataTransferSrv); err != nil {
+		log.Fatalf("⚠ Failed to sync: %v.", err)
+	}
+
+	log.Println("✓ Organization successfully synchronized.")
+}
+
+func applyPlanAction(
+	ctx context.Context,
+	opt *options,
+	orgName string,
+	directorySrv glib.DirectoryClient,
+	licensingSrv glib.LicensingClient,
+	groupsSettingsSrv glib.GroupsSettingsClient,
+	budget *glib.RetryBudget,
+) {
+	log.Printf("► Loading plan from %q…", opt.applyPlanFile)
+	plan, err := sync.LoadPlanFromFile(opt.applyPlanFile)
+	if err != nil {
+		log.Fatalf("⚠ Failed to load plan: %v.", err)
+	}
+
+	if plan.Organization != orgName {
+		log.Fatalf("⚠ Plan was created for organization %q, not %q.", plan.Organization, orgName)
+	}
+
+	if plan.Empty() {
+		log.Println("✓ Plan contains no changes.")
+		return
+	}
+
+	log.Println("⇄ Planned changes:")
+	plan.Log()
+
+	// the plan might have been created with a different configuration
+	protected := protectedResources(opt)
+	if err := sync.CheckProtection(plan, &protected); err != nil {
+		log.Fatalf("⚠ Aborting, plan removes protected resources: %v.", err)
+	}
+
+	if err := sync.CheckDeletions(plan, opt.maxDeletions, opt.maxDeletionPercent); err != nil {
+		log.Fatalf("⚠ Aborting, too many deletions: %v.", err)
+	}
+
+	if !opt.confirm {
+		log.Println("⚠ Run again with -confirm to apply the changes above.")
+		return
+	}
+
+	dataTransferSrv := dataTransferService(ctx, opt, plan, budget)
+
+	log.Println("► Applying changes…")
+	if err := sync.ApplyPlan(ctx, plan, directorySrv, licensingSrv, groupsSettingsSrv, dataTransferSrv); err != nil {
+		log.Fatalf("⚠ Failed to apply plan: %v.", err)
+	}
+
+	log.Println("✓ Plan successfully applied.")
+}
+
+// protectedResources returns the protected resources of all configs, plus
+// the account GMan is acting as, which must never be removed.
+func protectedResources(opt *options) config.Protected {
+	protected := config.Protected{
+		OrgUnits: opt.orgUnitsConfig.Protected.OrgUnits,
+	}
+
+	if opt.usersConfig != nil {
+		protected.Users = append(protected.Users, opt.usersConfig.Protected.Users...)
+	}
+
+	if opt.impersonatedUserEmail != "" {
+		protected.Users = append(protected.Users, opt.impersonatedUserEmail)
+	}
+
+	if opt.groupsConfig != nil {
+		protected.Groups = opt.groupsConfig.Protected.Groups
+	}
+
+	return protected
+}
+
+// dataTransferService creates the DataTransfer API client, which needs a
+// read/write scope of its own, only if the plan transfers any data.
+func dataTransferService(ctx context.Context, opt *options, plan *sync.Plan, budget *glib.RetryBudget) glib.DataTransferClient {
+	if !plan.TransfersData() {
+		return nil
+	}
+
+	dataTransferSrv, err := glib.NewDataTransferService(ctx, opt.clientSecretFile, opt.impersonatedUserEmail, opt.apiEndpoint, opt.throttleRequests, budget)
+	if err != nil {
+		log.Fatalf("⚠ Failed to create GSuite DataTransfer API client: %v", err)
+	}
+
+	return dataTransferSrv
 }
 
 func exportAction(
 	ctx context.Context,
 	opt *options,
-	directorySrv *glib.DirectoryService,
-	licensingSrv *glib.LicensingService,
-	groupsSettingsSrv *glib.GroupsSettingsService,
+	directorySrv glib.DirectoryClient,
+	licensingSrv glib.LicensingClient,
+	groupsSettingsSrv glib.GroupsSettingsClient,
 ) {
 	log.Println("► Exporting organizational units…")
-	orgUnits, err := export.ExportOrgUnits(ctx, directorySrv)
+	orgUnits, err := export.ExportOrgUnits(ctx, directorySrv, &opt.orgUnitsConfig.Ignore)
 	if err != nil {
 		log.Fatalf("⚠ Failed to export: %v.", err)
 	}
@@ -259,7 +512,7 @@ func exportAction(
 	users := []config.User{}
 	if opt.usersConfigFile != "" {
 		log.Println("► Exporting users…")
-		users, err = export.ExportUsers(ctx, directorySrv, licensingSrv, opt.licenseStatus)
+		users, err = export.ExportUsers(ctx, directorySrv, licensingSrv, opt.licenseStatus, &opt.usersConfig.Ignore)
 		if err != nil {
 			log.Fatalf("⚠ Failed to export: %v.", err)
 		}
@@ -268,7 +521,7 @@ func exportAction(
 	groups := []config.Group{}
 	if opt.groupsConfigFile != "" {
 		log.Println("► Exporting groups…")
-		groups, err = export.ExportGroups(ctx, directorySrv, groupsSettingsSrv)
+		groups, err = export.ExportGroups(ctx, directorySrv, groupsSettingsSrv, opt.groupsConfig, opt.concurrency)
 		if err != nil {
 			log.Fatalf("⚠ Failed to export: %v.", err)
 		}
@@ -328,6 +581,14 @@ func validateAction(opt *options) bool {
 		valid = false
 	}
 
+	if errs := opt.selection.Validate(); errs != nil {
+		log.Println("⚠ Selection is invalid:")
+		for _, e := range errs {
+			log.Printf("  - %v", e)
+		}
+		valid = false
+	}
+
 	if opt.usersConfig != nil {
 		if errs := opt.usersConfig.ValidateUsers(); errs != nil {
 			log.Println("⚠ User configuration is invalid:")
@@ -348,9 +609,75 @@ func validateAction(opt *options) bool {
 		}
 	}
 
+	errs := config.ValidateReferences(
+		config.File{Config: opt.orgUnitsConfig, Filename: opt.orgUnitsConfigFile},
+		config.File{Config: opt.usersConfig, Filename: opt.usersConfigFile},
+		config.File{Config: opt.groupsConfig, Filename: opt.groupsConfigFile},
+	)
+	if errs != nil {
+		log.Println("⚠ Configurations refer to undefined resources:")
+		for _, e := range errs {
+			log.Printf("  - %v", e)
+		}
+		valid = false
+	}
+
+	errs = config.ValidateEmails(
+		config.File{Config: opt.usersConfig, Filename: opt.usersConfigFile},
+		config.File{Config: opt.groupsConfig, Filename: opt.groupsConfigFile},
+	)
+	if errs != nil {
+		log.Println("⚠ Email addresses are not unique:")
+		for _, e := range errs {
+			log.Printf("  - %v", e)
+		}
+		valid = false
+	}
+
+	// mixed case emails are only a warning, they are used in lowercase
+	warnMixedCaseEmails(opt.usersConfigFile, opt.usersConfig)
+	if opt.groupsConfigFile != opt.usersConfigFile {
+		warnMixedCaseEmails(opt.groupsConfigFile, opt.groupsConfig)
+	}
+
+	warnExpiringMembers(opt.groupsConfigFile, opt.groupsConfig, opt.expiryWarningDays)
+
 	return valid
 }
 
+func warnMixedCaseEmails(filename string, cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	if emails := cfg.MixedCaseEmails(); len(emails) > 0 {
+		log.Printf("⚠ %s contains emails in mixed case, they are treated as lowercase:", filename)
+		for _, email := range emails {
+			log.Printf("  - %s", email)
+		}
+	}
+}
+
+// warnExpiringMembers lists the memberships that expire soon; expired
+// ones are only removed, but should be cleaned up from the configuration.
+func warnExpiringMembers(filename string, cfg *config.Config, days int) {
+	if cfg == nil {
+		return
+	}
+
+	now := time.Now()
+	if expirations := cfg.Expirations(now.AddDate(0, 0, days)); len(expirations) > 0 {
+		log.Printf("⚠ %s contains group memberships that have expired or expire within %d days:", filename, days)
+		for _, expiration := range expirations {
+			if expiration.Expired(now) {
+				log.Printf("  - %s in %s expired on %s", expiration.Member.Email, expiration.Group, expiration.Member.Expires)
+			} else {
+				log.Printf("  - %s in %s expires on %s", expiration.Member.Email, expiration.Group, expiration.Member.Expires)
+			}
+		}
+	}
+}
+
 func getScopes(readonly bool) []string {
 	if readonly {
 		return []string{
@@ -372,3 +699,27 @@ func getScopes(readonly bool) []string {
 		directoryv1.AdminDirectoryUserschemaScope,
 	}
 }
+
+func hasCustomerMembers(cfg *config.Config) bool {
+	for _, group := range cfg.Groups {
+		for _, member := range group.Members {
+			if member.Type == config.MemberTypeCustomer {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
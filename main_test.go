@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/emulator"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+// runMainEnv makes the test binary run main() instead of the tests, so
+// that the tests can run GMan as a separate process, see gman.
+const runMainEnv = "GMAN_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// gman runs GMan with the given flags against the emulator and returns
+// its output and whether it succeeded.
+func gman(t *testing.T, endpoint string, args ...string) (string, bool) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], append([]string{"-api-endpoint", endpoint, "-throttle-requests", "0"}, args...)...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+
+	output, err := cmd.CombinedOutput()
+	if _, failed := err.(*exec.ExitError); err != nil && !failed {
+		t.Fatalf("Failed to run GMan: %v", err)
+	}
+
+	return string(output), err == nil
+}
+
+func mustGMan(t *testing.T, endpoint string, args ...string) string {
+	t.Helper()
+
+	output, ok := gman(t, endpoint, args...)
+	if !ok {
+		t.Fatalf("GMan %s failed:\n%s", strings.Join(args, " "), output)
+	}
+
+	return output
+}
+
+// writeConfig writes a config file to the directory and returns its path.
+func writeConfig(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	return filename
+}
+
+// tenantState describes the org units, users and groups of the
+// workspace, one resource per line, sorted.
+func tenantState(t *testing.T, ws *fake.Workspace) []string {
+	t.Helper()
+
+	ctx := context.Background()
+	state := []string{}
+
+	orgUnits, err := ws.ListOrgUnits(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list org units: %v", err)
+	}
+
+	for _, orgUnit := range orgUnits {
+		state = append(state, fmt.Sprintf("orgunit %s", orgUnit.OrgUnitPath))
+	}
+
+	users, err := ws.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+
+	for _, user := range users {
+		state = append(state, fmt.Sprintf("user %s in %s", user.PrimaryEmail, user.OrgUnitPath))
+
+		aliases, err := ws.GetUserAliases(ctx, user)
+		if err != nil {
+			t.Fatalf("Failed to get aliases: %v", err)
+		}
+
+		for _, alias := range aliases {
+			state = append(state, fmt.Sprintf("alias %s/%s", user.PrimaryEmail, alias))
+		}
+	}
+
+	for _, license := range config.AllLicenses {
+		emails, err := ws.LicenseUsages(ctx, license)
+		if err != nil {
+			t.Fatalf("Failed to get license usages: %v", err)
+		}
+
+		for _, email := range emails {
+			state = append(state, fmt.Sprintf("license %s/%s", email, license.Name))
+		}
+	}
+
+	groups, err := ws.ListGroups(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list groups: %v", err)
+	}
+
+	for _, group := range groups {
+		settings, err := ws.GetSettings(ctx, group.Email)
+		if err != nil {
+			t.Fatalf("Failed to get settings: %v", err)
+		}
+
+		state = append(state, fmt.Sprintf("group %s (%s)", group.Email, settings.WhoCanPostMessage))
+
+		aliases, err := ws.GetGroupAliases(ctx, group)
+		if err != nil {
+			t.Fatalf("Failed to get aliases: %v", err)
+		}
+
+		for _, alias := range aliases {
+			state = append(state, fmt.Sprintf("groupalias %s/%s", group.Email, alias))
+		}
+
+		members, err := ws.ListMembers(ctx, group)
+		if err != nil {
+			t.Fatalf("Failed to list members: %v", err)
+		}
+
+		for _, member := range members {
+			state = append(state, fmt.Sprintf("member %s/%s (%s)", group.Email, member.Email, member.Role))
+		}
+	}
+
+	sort.Strings(state)
+
+	return state
+}
+
+const (
+	e2eOrgUnits = `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+`
+
+	e2eUsers = `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Engineering/Platform
+    aliases: [jd@example.com]
+    licenses: [GoogleWorkspaceBusinessStarter]
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+`
+
+	e2eGroups = `
+organization: example
+groups:
+  - name: Team
+    email: team@example.com
+    aliases: [crew@example.com]
+    whoCanPostMessage: ALL_IN_DOMAIN_CAN_POST
+    members:
+      - email: jane@example.com
+        role: OWNER
+      - email: bob@example.com
+`
+)
+
+func TestEndToEnd(t *testing.T) {
+	ws := fake.NewWorkspace("example", config.AllLicenses)
+
+	server := emulator.NewServer(ws)
+	defer server.Close()
+
+	endpoint := server.URL + "/"
+	dir := t.TempDir()
+
+	orgUnitsConfig := writeConfig(t, dir, "orgunits.yaml", e2eOrgUnits)
+	usersConfig := writeConfig(t, dir, "users.yaml", e2eUsers)
+	groupsConfig := writeConfig(t, dir, "groups.yaml", e2eGroups)
+	planFile := filepath.Join(dir, "plan.json")
+
+	configFlags := []string{
+		"-orgunits-config", orgUnitsConfig,
+		"-users-config", usersConfig,
+		"-groups-config", groupsConfig,
+	}
+
+	// a dry-run saves the plan, but changes nothing
+	mustGMan(t, endpoint, append(configFlags, "-plan-out", planFile)...)
+
+	if state := tenantState(t, ws); len(state) > 0 {
+		t.Fatalf("Expected a dry-run to change nothing, but got %q", state)
+	}
+
+	mustGMan(t, endpoint, append(configFlags, "-apply-plan", planFile, "-confirm")...)
+
+	expected := []string{
+		"alias jane@example.com/jd@example.com",
+		"group team@example.com (ALL_IN_DOMAIN_CAN_POST)",
+		"groupalias team@example.com/crew@example.com",
+		"license jane@example.com/GoogleWorkspaceBusinessStarter",
+		"member team@example.com/bob@example.com (MEMBER)",
+		"member team@example.com/jane@example.com (OWNER)",
+		"orgunit /Engineering",
+		"orgunit /Engineering/Platform",
+		"user bob@example.com in /",
+		"user jane@example.com in /Engineering/Platform",
+	}
+
+	if state := tenantState(t, ws); !reflect.DeepEqual(state, expected) {
+		t.Fatalf("Expected tenant state\n%q\nbut got\n%q", expected, state)
+	}
+
+	if output := mustGMan(t, endpoint, configFlags...); !strings.Contains(output, "organization is in sync") {
+		t.Fatalf("Expected no changes after applying the plan, but got:\n%s", output)
+	}
+
+	// removing all users exceeds the default deletion limit
+	writeConfig(t, dir, "users.yaml", "organization: example\n")
+	writeConfig(t, dir, "groups.yaml", strings.SplitAfter(e2eGroups, "members:\n")[0])
+
+	if output, ok := gman(t, endpoint, append(configFlags, "-confirm")...); ok || !strings.Contains(output, "too many deletions") {
+		t.Fatalf("Expected the deletion limit to abort the sync, but got:\n%s", output)
+	}
+
+	if state := tenantState(t, ws); !reflect.DeepEqual(state, expected) {
+		t.Fatalf("Expected an aborted sync to change nothing, but got\n%q", state)
+	}
+
+	// removing bob stays within the limit
+	writeConfig(t, dir, "users.yaml", strings.SplitAfter(e2eUsers, "[GoogleWorkspaceBusinessStarter]\n")[0])
+	writeConfig(t, dir, "groups.yaml", strings.SplitAfter(e2eGroups, "role: OWNER\n")[0])
+
+	mustGMan(t, endpoint, append(configFlags, "-confirm")...)
+
+	expected = []string{
+		"alias jane@example.com/jd@example.com",
+		"group team@example.com (ALL_IN_DOMAIN_CAN_POST)",
+		"groupalias team@example.com/crew@example.com",
+		"license jane@example.com/GoogleWorkspaceBusinessStarter",
+		"member team@example.com/jane@example.com (OWNER)",
+		"orgunit /Engineering",
+		"orgunit /Engineering/Platform",
+		"user jane@example.com in /Engineering/Platform",
+	}
+
+	if state := tenantState(t, ws); !reflect.DeepEqual(state, expected) {
+		t.Fatalf("Expected tenant state\n%q\nbut got\n%q", expected, state)
+	}
+}
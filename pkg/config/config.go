@@ -20,7 +20,9 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
+	"path"
 	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -29,6 +31,7 @@ import (
 const (
 	SchemaName              = "gman"
 	PasswordHashCustomField = "passwordHash"
+	DeleteAfterCustomField  = "deleteAfter"
 )
 
 const (
@@ -37,6 +40,7 @@ const (
 	GroupOptionAllMembersCanContact      = "ALL_MEMBERS_CAN_CONTACT"
 	GroupOptionAllInDomainCanContact     = "ALL_IN_DOMAIN_CAN_CONTACT"
 	GroupOptionAnyoneCanContact          = "ANYONE_CAN_CONTACT"
+	GroupOptionAllOwnersCanContact       = "ALL_OWNERS_CAN_CONTACT"
 	GroupOptionWhoCanContactOwnerDefault = GroupOptionAllInDomainCanContact
 
 	// WhoCanViewMembership
@@ -68,10 +72,106 @@ const (
 	GroupOptionAnyoneCanJoin      = "ANYONE_CAN_JOIN"
 	GroupOptionWhoCanJoinDefault  = GroupOptionInvitedCanJoin
 
+	// WhoCanModerateMembers, WhoCanModerateContent and WhoCanAssistContent
+	GroupOptionAllMembers                   = "ALL_MEMBERS"
+	GroupOptionOwnersAndManagers            = "OWNERS_AND_MANAGERS"
+	GroupOptionManagersOnly                 = "MANAGERS_ONLY"
+	GroupOptionOwnersOnly                   = "OWNERS_ONLY"
+	GroupOptionNone                         = "NONE"
+	GroupOptionWhoCanModerateMembersDefault = GroupOptionOwnersAndManagers
+	GroupOptionWhoCanModerateContentDefault = GroupOptionOwnersAndManagers
+	GroupOptionWhoCanAssistContentDefault   = GroupOptionOwnersAndManagers
+
+	// WhoCanDiscoverGroup
+	GroupOptionAnyoneCanDiscover          = "ANYONE_CAN_DISCOVER"
+	GroupOptionAllInDomainCanDiscover     = "ALL_IN_DOMAIN_CAN_DISCOVER"
+	GroupOptionAllMembersCanDiscover      = "ALL_MEMBERS_CAN_DISCOVER"
+	GroupOptionWhoCanDiscoverGroupDefault = GroupOptionAllInDomainCanDiscover
+
+	// WhoCanLeaveGroup
+	GroupOptionAllManagersCanLeave     = "ALL_MANAGERS_CAN_LEAVE"
+	GroupOptionAllMembersCanLeave      = "ALL_MEMBERS_CAN_LEAVE"
+	GroupOptionNoneCanLeave            = "NONE_CAN_LEAVE"
+	GroupOptionWhoCanLeaveGroupDefault = GroupOptionAllMembersCanLeave
+
+	// WhoCanViewGroup
+	GroupOptionAnyoneCanView          = "ANYONE_CAN_VIEW"
+	GroupOptionAllInDomainCanView     = "ALL_IN_DOMAIN_CAN_VIEW"
+	GroupOptionAllMembersCanView      = "ALL_MEMBERS_CAN_VIEW"
+	GroupOptionAllManagersCanView     = "ALL_MANAGERS_CAN_VIEW"
+	GroupOptionWhoCanViewGroupDefault = GroupOptionAllMembersCanView
+
+	// MessageModerationLevel
+	GroupOptionModerateAllMessages           = "MODERATE_ALL_MESSAGES"
+	GroupOptionModerateNonMembers            = "MODERATE_NON_MEMBERS"
+	GroupOptionModerateNewMembers            = "MODERATE_NEW_MEMBERS"
+	GroupOptionModerateNone                  = "MODERATE_NONE"
+	GroupOptionMessageModerationLevelDefault = GroupOptionModerateNone
+
+	// SpamModerationLevel
+	GroupOptionSpamAllow                  = "ALLOW"
+	GroupOptionSpamModerate               = "MODERATE"
+	GroupOptionSpamSilentlyModerate       = "SILENTLY_MODERATE"
+	GroupOptionSpamReject                 = "REJECT"
+	GroupOptionSpamModerationLevelDefault = GroupOptionSpamModerate
+
+	// ReplyTo
+	GroupOptionReplyToCustom   = "REPLY_TO_CUSTOM"
+	GroupOptionReplyToSender   = "REPLY_TO_SENDER"
+	GroupOptionReplyToList     = "REPLY_TO_LIST"
+	GroupOptionReplyToOwner    = "REPLY_TO_OWNER"
+	GroupOptionReplyToIgnore   = "REPLY_TO_IGNORE"
+	GroupOptionReplyToManagers = "REPLY_TO_MANAGERS"
+	GroupOptionReplyToDefault  = GroupOptionReplyToIgnore
+
+	// DefaultSender
+	GroupOptionDefaultSenderSelf    = "DEFAULT_SELF"
+	GroupOptionDefaultSenderGroup   = "GROUP"
+	GroupOptionDefaultSenderDefault = GroupOptionDefaultSenderSelf
+
+	// boolean settings
+	GroupOptionAllowExternalMembersDefault        = false
+	GroupOptionIsArchivedDefault                  = false
+	GroupOptionArchiveOnlyDefault                 = false
+	GroupOptionAllowWebPostingDefault             = true
+	GroupOptionEnableCollaborativeInboxDefault    = false
+	GroupOptionFavoriteRepliesOnTopDefault        = true
+	GroupOptionIncludeInGlobalAddressListDefault  = true
+	GroupOptionMembersCanPostAsTheGroupDefault    = false
+	GroupOptionIncludeCustomFooterDefault         = false
+	GroupOptionSendMessageDenyNotificationDefault = false
+
 	// membership roles
 	MemberRoleOwner   = "OWNER"
 	MemberRoleManager = "MANAGER"
 	MemberRoleMember  = "MEMBER"
+
+	// membership types
+	MemberTypeUser     = "USER"
+	MemberTypeGroup    = "GROUP"
+	MemberTypeCustomer = "CUSTOMER"
+	MemberTypeExternal = "EXTERNAL"
+
+	// membership delivery settings
+	MemberDeliveryAllMail  = "ALL_MAIL"
+	MemberDeliveryDaily    = "DAILY"
+	MemberDeliveryDigest   = "DIGEST"
+	MemberDeliveryDisabled = "DISABLED"
+	MemberDeliveryNone     = "NONE"
+	MemberDeliveryDefault  = MemberDeliveryAllMail
+
+	// membership modes: authoritative groups have exactly the configured
+	// members, additive groups never lose members that are not configured
+	// and ownersOnly groups only have their owners and managers managed
+	MembershipModeAuthoritative = "authoritative"
+	MembershipModeAdditive      = "additive"
+	MembershipModeOwnersOnly    = "ownersOnly"
+	MembershipModeDefault       = MembershipModeAuthoritative
+
+	// group settings policies
+	GroupSettingsManaged  = "managed"
+	GroupSettingsExplicit = "explicit"
+	GroupSettingsDefault  = GroupSettingsManaged
 )
 
 var (
@@ -80,6 +180,7 @@ var (
 		GroupOptionAllMembersCanContact,
 		GroupOptionAllInDomainCanContact,
 		GroupOptionAnyoneCanContact,
+		GroupOptionAllOwnersCanContact,
 	)
 
 	allWhoCanViewMembershipOptions = sets.NewString(
@@ -111,42 +212,199 @@ var (
 		GroupOptionAnyoneCanJoin,
 	)
 
+	allWhoCanModerateOptions = sets.NewString(
+		GroupOptionAllMembers,
+		GroupOptionOwnersAndManagers,
+		GroupOptionOwnersOnly,
+		GroupOptionNone,
+	)
+
+	allWhoCanAssistContentOptions = sets.NewString(
+		GroupOptionAllMembers,
+		GroupOptionOwnersAndManagers,
+		GroupOptionManagersOnly,
+		GroupOptionOwnersOnly,
+		GroupOptionNone,
+	)
+
+	allWhoCanDiscoverGroupOptions = sets.NewString(
+		GroupOptionAnyoneCanDiscover,
+		GroupOptionAllInDomainCanDiscover,
+		GroupOptionAllMembersCanDiscover,
+	)
+
+	allWhoCanLeaveGroupOptions = sets.NewString(
+		GroupOptionAllManagersCanLeave,
+		GroupOptionAllMembersCanLeave,
+		GroupOptionNoneCanLeave,
+	)
+
+	allWhoCanViewGroupOptions = sets.NewString(
+		GroupOptionAnyoneCanView,
+		GroupOptionAllInDomainCanView,
+		GroupOptionAllMembersCanView,
+		GroupOptionAllManagersCanView,
+	)
+
+	allMessageModerationLevels = sets.NewString(
+		GroupOptionModerateAllMessages,
+		GroupOptionModerateNonMembers,
+		GroupOptionModerateNewMembers,
+		GroupOptionModerateNone,
+	)
+
+	allSpamModerationLevels = sets.NewString(
+		GroupOptionSpamAllow,
+		GroupOptionSpamModerate,
+		GroupOptionSpamSilentlyModerate,
+		GroupOptionSpamReject,
+	)
+
+	allReplyToOptions = sets.NewString(
+		GroupOptionReplyToCustom,
+		GroupOptionReplyToSender,
+		GroupOptionReplyToList,
+		GroupOptionReplyToOwner,
+		GroupOptionReplyToIgnore,
+		GroupOptionReplyToManagers,
+	)
+
+	allDefaultSenderOptions = sets.NewString(
+		GroupOptionDefaultSenderSelf,
+		GroupOptionDefaultSenderGroup,
+	)
+
+	allGroupSettingsPolicies = sets.NewString(
+		GroupSettingsManaged,
+		GroupSettingsExplicit,
+	)
+
 	allMemberRoles = sets.NewString(
 		MemberRoleOwner,
 		MemberRoleManager,
 		MemberRoleMember,
 	)
+
+	allMemberTypes = sets.NewString(
+		MemberTypeUser,
+		MemberTypeGroup,
+		MemberTypeCustomer,
+		MemberTypeExternal,
+	)
+
+	allMembershipModes = sets.NewString(
+		MembershipModeAuthoritative,
+		MembershipModeAdditive,
+		MembershipModeOwnersOnly,
+	)
+
+	allMemberDeliverySettings = sets.NewString(
+		MemberDeliveryAllMail,
+		MemberDeliveryDaily,
+		MemberDeliveryDigest,
+		MemberDeliveryDisabled,
+		MemberDeliveryNone,
+	)
 )
 
 type Config struct {
-	Organization string    `yaml:"organization"`
-	OrgUnits     []OrgUnit `yaml:"orgUnits,omitempty"`
-	Users        []User    `yaml:"users,omitempty"`
-	Groups       []Group   `yaml:"groups,omitempty"`
-	Licenses     []License `yaml:"licenses,omitempty"`
+	Organization string    `yaml:"organization" json:"organization"`
+	OrgUnits     []OrgUnit `yaml:"orgUnits,omitempty" json:"orgUnits,omitempty"`
+	Users        []User    `yaml:"users,omitempty" json:"users,omitempty"`
+	Groups       []Group   `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Licenses     []License `yaml:"licenses,omitempty" json:"licenses,omitempty"`
+	Protected    Protected `yaml:"protected,omitempty" json:"protected,omitempty"`
+	Ignore       Ignore    `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+
+	UserRemovalPolicy UserRemovalPolicy `yaml:"userRemovalPolicy,omitempty" json:"userRemovalPolicy,omitempty"`
+	DataTransfer      *DataTransfer     `yaml:"dataTransfer,omitempty" json:"dataTransfer,omitempty"`
+
+	// GroupSettingsPolicy decides what happens to the settings that a group
+	// does not configure: with "managed", they are reset to the defaults,
+	// with "explicit", they are left as they are.
+	GroupSettingsPolicy string `yaml:"groupSettingsPolicy,omitempty" json:"groupSettingsPolicy,omitempty"`
+
+	// GroupDefaults are used for all groups that do not configure them,
+	// taking precedence over GSuite's defaults.
+	GroupDefaults *GroupSettings `yaml:"groupDefaults,omitempty" json:"groupDefaults,omitempty"`
+
+	// mixedCaseEmails are the emails that were canonicalized when loading
+	// the configuration, see MixedCaseEmails.
+	mixedCaseEmails []string
+}
+
+// Protected contains glob patterns (see path.Match) for resources that
+// GMan must never delete, even if they are missing from the config.
+// Users and groups are matched by email, org units by their full path.
+type Protected struct {
+	Users    []string `yaml:"users,omitempty" json:"users,omitempty"`
+	Groups   []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+	OrgUnits []string `yaml:"orgUnits,omitempty" json:"orgUnits,omitempty"`
+}
+
+func (p *Protected) IsUserProtected(email string) bool {
+	return matchesAny(p.Users, email)
+}
+
+func (p *Protected) IsGroupProtected(email string) bool {
+	return matchesAny(p.Groups, email)
+}
+
+func (p *Protected) IsOrgUnitProtected(orgUnitPath string) bool {
+	return matchesAny(p.OrgUnits, orgUnitPath)
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); matched {
+			return true
+		}
+	}
+
+	return false
 }
 
 type OrgUnit struct {
-	Name              string `yaml:"name"`
-	Description       string `yaml:"description,omitempty"`
-	ParentOrgUnitPath string `yaml:"parentOrgUnitPath,omitempty"`
-	BlockInheritance  bool   `yaml:"blockInheritance,omitempty"`
+	Name              string `yaml:"name" json:"name"`
+	Description       string `yaml:"description,omitempty" json:"description,omitempty"`
+	ParentOrgUnitPath string `yaml:"parentOrgUnitPath,omitempty" json:"parentOrgUnitPath,omitempty"`
+	BlockInheritance  bool   `yaml:"blockInheritance,omitempty" json:"blockInheritance,omitempty"`
+
+	// PreviousPaths are former full paths of the org unit. If the org unit
+	// does not exist yet, but one at a previous path does, it is renamed
+	// or moved in place instead of being recreated.
+	PreviousPaths []string `yaml:"previousPaths,omitempty" json:"previousPaths,omitempty"`
+}
+
+// OrgUnitPath returns the full path of the org unit.
+func (o *OrgUnit) OrgUnitPath() string {
+	return strings.TrimSuffix(o.ParentOrgUnitPath, "/") + "/" + o.Name
 }
 
 type User struct {
-	FirstName     string   `yaml:"givenName"`
-	LastName      string   `yaml:"familyName"`
-	PrimaryEmail  string   `yaml:"primaryEmail"`
-	Aliases       []string `yaml:"aliases,omitempty"`
-	Phones        []string `yaml:"phones,omitempty"`
-	RecoveryPhone string   `yaml:"recoveryPhone,omitempty"`
-	RecoveryEmail string   `yaml:"recoveryEmail,omitempty"`
-	OrgUnitPath   string   `yaml:"orgUnitPath,omitempty"`
-	Licenses      []string `yaml:"licenses,omitempty"`
-	Employee      Employee `yaml:"employeeInfo,omitempty"`
-	Location      Location `yaml:"location,omitempty"`
-	Address       string   `yaml:"address,omitempty"`
-	Password      string   `yaml:"password,omitempty"`
+	FirstName     string   `yaml:"givenName" json:"givenName"`
+	LastName      string   `yaml:"familyName" json:"familyName"`
+	PrimaryEmail  string   `yaml:"primaryEmail" json:"primaryEmail"`
+	Aliases       []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Phones        []string `yaml:"phones,omitempty" json:"phones,omitempty"`
+	RecoveryPhone string   `yaml:"recoveryPhone,omitempty" json:"recoveryPhone,omitempty"`
+	RecoveryEmail string   `yaml:"recoveryEmail,omitempty" json:"recoveryEmail,omitempty"`
+	OrgUnitPath   string   `yaml:"orgUnitPath,omitempty" json:"orgUnitPath,omitempty"`
+	Licenses      []string `yaml:"licenses,omitempty" json:"licenses,omitempty"`
+	Employee      Employee `yaml:"employeeInfo,omitempty" json:"employeeInfo,omitempty"`
+	Location      Location `yaml:"location,omitempty" json:"location,omitempty"`
+	Address       string   `yaml:"address,omitempty" json:"address,omitempty"`
+	Password      string   `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// PasswordHash and HashFunction replace the plaintext password in
+	// sync plans, so that plans never contain passwords.
+	PasswordHash string `yaml:"-" json:"passwordHash,omitempty"`
+	HashFunction string `yaml:"-" json:"hashFunction,omitempty"`
+
+	// PreviousEmails are former primary emails of the user. A live user
+	// with one of them (or else with the same employee ID) is renamed
+	// instead of being replaced by a new account.
+	PreviousEmails []string `yaml:"previousEmails,omitempty" json:"previousEmails,omitempty"`
 }
 
 func (u *User) Sort() {
@@ -156,9 +414,9 @@ func (u *User) Sort() {
 }
 
 type Location struct {
-	Building     string `yaml:"building,omitempty"`
-	Floor        string `yaml:"floor,omitempty"`
-	FloorSection string `yaml:"floorSection,omitempty"`
+	Building     string `yaml:"building,omitempty" json:"building,omitempty"`
+	Floor        string `yaml:"floor,omitempty" json:"floor,omitempty"`
+	FloorSection string `yaml:"floorSection,omitempty" json:"floorSection,omitempty"`
 }
 
 func (l *Location) Empty() bool {
@@ -166,12 +424,12 @@ func (l *Location) Empty() bool {
 }
 
 type Employee struct {
-	EmployeeID   string `yaml:"id,omitempty"`
-	Department   string `yaml:"department,omitempty"`
-	JobTitle     string `yaml:"jobTitle,omitempty"`
-	Type         string `yaml:"type,omitempty"`
-	CostCenter   string `yaml:"costCenter,omitempty"`
-	ManagerEmail string `yaml:"managerEmail,omitempty"`
+	EmployeeID   string `yaml:"id,omitempty" json:"id,omitempty"`
+	Department   string `yaml:"department,omitempty" json:"department,omitempty"`
+	JobTitle     string `yaml:"jobTitle,omitempty" json:"jobTitle,omitempty"`
+	Type         string `yaml:"type,omitempty" json:"type,omitempty"`
+	CostCenter   string `yaml:"costCenter,omitempty" json:"costCenter,omitempty"`
+	ManagerEmail string `yaml:"managerEmail,omitempty" json:"managerEmail,omitempty"`
 }
 
 func (e *Employee) Empty() bool {
@@ -179,28 +437,112 @@ func (e *Employee) Empty() bool {
 }
 
 type Group struct {
-	Name                 string   `yaml:"name"`
-	Email                string   `yaml:"email"`
-	Description          string   `yaml:"description,omitempty"`
-	WhoCanContactOwner   string   `yaml:"whoCanContactOwner,omitempty"`
-	WhoCanViewMembership string   `yaml:"whoCanViewMembers,omitempty"`
-	WhoCanApproveMembers string   `yaml:"whoCanApproveMembers,omitempty"`
-	WhoCanPostMessage    string   `yaml:"whoCanPostMessage,omitempty"`
-	WhoCanJoin           string   `yaml:"whoCanJoin,omitempty"`
-	AllowExternalMembers bool     `yaml:"allowExternalMembers,omitempty"`
-	IsArchived           bool     `yaml:"isArchived,omitempty"`
-	Members              []Member `yaml:"members,omitempty"`
+	Name        string   `yaml:"name" json:"name"`
+	Email       string   `yaml:"email" json:"email"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Aliases     []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+
+	GroupSettings `yaml:",inline" json:",inline"`
+
+	// MembershipMode decides which of the live members are managed by GMan,
+	// see ManagesMember.
+	MembershipMode string `yaml:"membershipMode,omitempty" json:"membershipMode,omitempty"`
+
+	Members []Member `yaml:"members,omitempty" json:"members,omitempty"`
+
+	// PreviousEmails are former emails of the group. A live group with one
+	// of them is renamed instead of being replaced by a new, empty group.
+	PreviousEmails []string `yaml:"previousEmails,omitempty" json:"previousEmails,omitempty"`
+}
+
+// GroupSettings are the group's settings in the Groups Settings API.
+// Settings that Google deprecated in favour of whoCanModerateMembers,
+// whoCanModerateContent and whoCanAssistContent are not supported,
+// except for whoCanApproveMembers. Boolean and free-text settings are
+// pointers, so that they can be told apart from unset ones.
+type GroupSettings struct {
+	// access
+	WhoCanContactOwner    string `yaml:"whoCanContactOwner,omitempty" json:"whoCanContactOwner,omitempty"`
+	WhoCanViewMembership  string `yaml:"whoCanViewMembers,omitempty" json:"whoCanViewMembers,omitempty"`
+	WhoCanApproveMembers  string `yaml:"whoCanApproveMembers,omitempty" json:"whoCanApproveMembers,omitempty"`
+	WhoCanPostMessage     string `yaml:"whoCanPostMessage,omitempty" json:"whoCanPostMessage,omitempty"`
+	WhoCanJoin            string `yaml:"whoCanJoin,omitempty" json:"whoCanJoin,omitempty"`
+	WhoCanModerateMembers string `yaml:"whoCanModerateMembers,omitempty" json:"whoCanModerateMembers,omitempty"`
+	WhoCanModerateContent string `yaml:"whoCanModerateContent,omitempty" json:"whoCanModerateContent,omitempty"`
+	WhoCanAssistContent   string `yaml:"whoCanAssistContent,omitempty" json:"whoCanAssistContent,omitempty"`
+	WhoCanDiscoverGroup   string `yaml:"whoCanDiscoverGroup,omitempty" json:"whoCanDiscoverGroup,omitempty"`
+	WhoCanLeaveGroup      string `yaml:"whoCanLeaveGroup,omitempty" json:"whoCanLeaveGroup,omitempty"`
+	WhoCanViewGroup       string `yaml:"whoCanViewGroup,omitempty" json:"whoCanViewGroup,omitempty"`
+	AllowExternalMembers  *bool  `yaml:"allowExternalMembers,omitempty" json:"allowExternalMembers,omitempty"`
+	AllowWebPosting       *bool  `yaml:"allowWebPosting,omitempty" json:"allowWebPosting,omitempty"`
+
+	// archive
+	IsArchived  *bool `yaml:"isArchived,omitempty" json:"isArchived,omitempty"`
+	ArchiveOnly *bool `yaml:"archiveOnly,omitempty" json:"archiveOnly,omitempty"`
+
+	// moderation
+	MessageModerationLevel             string  `yaml:"messageModerationLevel,omitempty" json:"messageModerationLevel,omitempty"`
+	SpamModerationLevel                string  `yaml:"spamModerationLevel,omitempty" json:"spamModerationLevel,omitempty"`
+	SendMessageDenyNotification        *bool   `yaml:"sendMessageDenyNotification,omitempty" json:"sendMessageDenyNotification,omitempty"`
+	DefaultMessageDenyNotificationText *string `yaml:"defaultMessageDenyNotificationText,omitempty" json:"defaultMessageDenyNotificationText,omitempty"`
+
+	// email options
+	ReplyTo                    string  `yaml:"replyTo,omitempty" json:"replyTo,omitempty"`
+	CustomReplyTo              *string `yaml:"customReplyTo,omitempty" json:"customReplyTo,omitempty"`
+	DefaultSender              string  `yaml:"defaultSender,omitempty" json:"defaultSender,omitempty"`
+	MembersCanPostAsTheGroup   *bool   `yaml:"membersCanPostAsTheGroup,omitempty" json:"membersCanPostAsTheGroup,omitempty"`
+	IncludeCustomFooter        *bool   `yaml:"includeCustomFooter,omitempty" json:"includeCustomFooter,omitempty"`
+	CustomFooterText           *string `yaml:"customFooterText,omitempty" json:"customFooterText,omitempty"`
+	IncludeInGlobalAddressList *bool   `yaml:"includeInGlobalAddressList,omitempty" json:"includeInGlobalAddressList,omitempty"`
+	EnableCollaborativeInbox   *bool   `yaml:"enableCollaborativeInbox,omitempty" json:"enableCollaborativeInbox,omitempty"`
+	FavoriteRepliesOnTop       *bool   `yaml:"favoriteRepliesOnTop,omitempty" json:"favoriteRepliesOnTop,omitempty"`
+	PrimaryLanguage            *string `yaml:"primaryLanguage,omitempty" json:"primaryLanguage,omitempty"`
 }
 
 func (g *Group) Sort() {
+	sort.Strings(g.Aliases)
 	sort.SliceStable(g.Members, func(i, j int) bool {
 		return g.Members[i].Email < g.Members[j].Email
 	})
 }
 
+// ManagesMember returns true if the group's membership mode allows GMan to
+// update or remove the live member: additive groups only manage configured
+// members, ownersOnly groups only owners and managers.
+func (g *Group) ManagesMember(member Member) bool {
+	switch g.MembershipMode {
+	case MembershipModeAdditive:
+		for _, m := range g.Members {
+			if m.Email == member.Email {
+				return true
+			}
+		}
+
+		return false
+
+	case MembershipModeOwnersOnly:
+		return member.Role == MemberRoleOwner || member.Role == MemberRoleManager
+
+	default:
+		return true
+	}
+}
+
 type Member struct {
-	Email string `yaml:"email"`
-	Role  string `yaml:"role,omitempty"`
+	// Email is empty for CUSTOMER members, which stand for all users of
+	// the organization.
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+	Role  string `yaml:"role,omitempty" json:"role,omitempty"`
+	Type  string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Delivery is how the member receives the group's messages. Members
+	// can change it themselves, so it is only managed if configured.
+	Delivery string `yaml:"delivery,omitempty" json:"delivery,omitempty"`
+
+	// Since and Expires optionally limit the membership to a period of
+	// time (YYYY-MM-DD, both inclusive), see Active.
+	Since   string `yaml:"since,omitempty" json:"since,omitempty"`
+	Expires string `yaml:"expires,omitempty" json:"expires,omitempty"`
 }
 
 func LoadFromFile(filename string) (*Config, error) {
@@ -29,7 +29,12 @@ import (
 )
 
 type CustomSchema struct {
-	PasswordHash string `json:"passwordHash"`
+	PasswordHash string `json:"passwordHash,omitempty"`
+
+	// DeleteAfter is the date (YYYY-MM-DD) after which a user that was
+	// suspended because of the suspendThenDeleteAfter removal policy
+	// is deleted.
+	DeleteAfter string `json:"deleteAfter,omitempty"`
 }
 
 func GetUserSchema(user *directoryv1.User) *CustomSchema {
@@ -133,14 +138,15 @@ func ToGSuiteUser(user *User, enableInsecurePasswords bool) *directoryv1.User {
 		}
 	}
 
-	if enableInsecurePasswords && user.Password != "" {
+	if enableInsecurePasswords && user.PasswordHash != "" {
 		customData := CustomSchema{
-			PasswordHash: HashPassword(user.Password),
+			PasswordHash: user.PasswordHash,
 		}
 
 		encoded, _ := json.Marshal(customData)
 		gsuiteUser.CustomSchemas[SchemaName] = encoded
-		gsuiteUser.Password = user.Password
+		gsuiteUser.Password = user.PasswordHash
+		gsuiteUser.HashFunction = user.HashFunction
 	}
 
 	return gsuiteUser
@@ -206,11 +212,14 @@ func ToConfigUser(gsuiteUser *directoryv1.User, userLicenses []License) (User, e
 	user := User{
 		FirstName:     gsuiteUser.Name.GivenName,
 		LastName:      gsuiteUser.Name.FamilyName,
-		PrimaryEmail:  primaryEmail,
+		PrimaryEmail:  CanonicalEmail(primaryEmail),
 		OrgUnitPath:   gsuiteUser.OrgUnitPath,
 		RecoveryPhone: gsuiteUser.RecoveryPhone,
 		RecoveryEmail: gsuiteUser.RecoveryEmail,
-		Aliases:       gsuiteUser.Aliases,
+	}
+
+	for _, alias := range gsuiteUser.Aliases {
+		user.Aliases = append(user.Aliases, CanonicalEmail(alias))
 	}
 
 	for _, phone := range apiUser.Phones {
@@ -236,7 +245,7 @@ func ToConfigUser(gsuiteUser *directoryv1.User, userLicenses []License) (User, e
 
 	for _, relation := range apiUser.Relations {
 		if relation.Type == "manager" {
-			user.Employee.ManagerEmail = relation.Value
+			user.Employee.ManagerEmail = CanonicalEmail(relation.Value)
 		}
 	}
 
@@ -271,41 +280,116 @@ func ToGSuiteGroup(group *Group) (*directoryv1.Group, *groupssettingsv1.Groups)
 	}
 
 	groupSettings := &groupssettingsv1.Groups{
-		WhoCanContactOwner:   group.WhoCanContactOwner,
-		WhoCanViewMembership: group.WhoCanViewMembership,
-		WhoCanApproveMembers: group.WhoCanApproveMembers,
-		WhoCanPostMessage:    group.WhoCanPostMessage,
-		WhoCanJoin:           group.WhoCanJoin,
-		IsArchived:           strconv.FormatBool(group.IsArchived),
-		AllowExternalMembers: strconv.FormatBool(group.AllowExternalMembers),
+		WhoCanContactOwner:          group.WhoCanContactOwner,
+		WhoCanViewMembership:        group.WhoCanViewMembership,
+		WhoCanApproveMembers:        group.WhoCanApproveMembers,
+		WhoCanPostMessage:           group.WhoCanPostMessage,
+		WhoCanJoin:                  group.WhoCanJoin,
+		WhoCanModerateMembers:       group.WhoCanModerateMembers,
+		WhoCanModerateContent:       group.WhoCanModerateContent,
+		WhoCanAssistContent:         group.WhoCanAssistContent,
+		WhoCanDiscoverGroup:         group.WhoCanDiscoverGroup,
+		WhoCanLeaveGroup:            group.WhoCanLeaveGroup,
+		WhoCanViewGroup:             group.WhoCanViewGroup,
+		AllowExternalMembers:        formatBoolSetting(group.AllowExternalMembers),
+		AllowWebPosting:             formatBoolSetting(group.AllowWebPosting),
+		IsArchived:                  formatBoolSetting(group.IsArchived),
+		ArchiveOnly:                 formatBoolSetting(group.ArchiveOnly),
+		MessageModerationLevel:      group.MessageModerationLevel,
+		SpamModerationLevel:         group.SpamModerationLevel,
+		SendMessageDenyNotification: formatBoolSetting(group.SendMessageDenyNotification),
+		ReplyTo:                     group.ReplyTo,
+		DefaultSender:               group.DefaultSender,
+		MembersCanPostAsTheGroup:    formatBoolSetting(group.MembersCanPostAsTheGroup),
+		IncludeCustomFooter:         formatBoolSetting(group.IncludeCustomFooter),
+		IncludeInGlobalAddressList:  formatBoolSetting(group.IncludeInGlobalAddressList),
+		EnableCollaborativeInbox:    formatBoolSetting(group.EnableCollaborativeInbox),
+		FavoriteRepliesOnTop:        formatBoolSetting(group.FavoriteRepliesOnTop),
+	}
+
+	// free-text settings are empty by default and need to be sent
+	// explicitly to be cleared; unset ones are omitted
+	textSettings := []struct {
+		name   string
+		value  *string
+		target *string
+	}{
+		{"CustomReplyTo", group.CustomReplyTo, &groupSettings.CustomReplyTo},
+		{"CustomFooterText", group.CustomFooterText, &groupSettings.CustomFooterText},
+		{"DefaultMessageDenyNotificationText", group.DefaultMessageDenyNotificationText, &groupSettings.DefaultMessageDenyNotificationText},
+		{"PrimaryLanguage", group.PrimaryLanguage, &groupSettings.PrimaryLanguage},
+	}
+
+	for _, setting := range textSettings {
+		if setting.value != nil {
+			*setting.target = *setting.value
+			groupSettings.ForceSendFields = append(groupSettings.ForceSendFields, setting.name)
+		}
 	}
 
 	return gsuiteGroup, groupSettings
 }
 
-func ToConfigGroup(gsuiteGroup *directoryv1.Group, settings *groupssettingsv1.Groups, members []*directoryv1.Member) (Group, error) {
-	allowExternalMembers, err := strconv.ParseBool(settings.AllowExternalMembers)
-	if err != nil {
-		return Group{}, fmt.Errorf("invalid 'AllowExternalMembers' value: %v", err)
+// ToConfigGroup converts a group with its settings, members and aliases;
+// the aliases are fetched separately, as they are not always included in
+// the group itself.
+func ToConfigGroup(gsuiteGroup *directoryv1.Group, settings *groupssettingsv1.Groups, members []*directoryv1.Member, aliases []string) (Group, error) {
+	group := Group{
+		Name:        gsuiteGroup.Name,
+		Email:       CanonicalEmail(gsuiteGroup.Email),
+		Description: gsuiteGroup.Description,
+		GroupSettings: GroupSettings{
+			WhoCanContactOwner:                 settings.WhoCanContactOwner,
+			WhoCanViewMembership:               settings.WhoCanViewMembership,
+			WhoCanApproveMembers:               settings.WhoCanApproveMembers,
+			WhoCanPostMessage:                  settings.WhoCanPostMessage,
+			WhoCanJoin:                         settings.WhoCanJoin,
+			WhoCanModerateMembers:              settings.WhoCanModerateMembers,
+			WhoCanModerateContent:              settings.WhoCanModerateContent,
+			WhoCanAssistContent:                settings.WhoCanAssistContent,
+			WhoCanDiscoverGroup:                settings.WhoCanDiscoverGroup,
+			WhoCanLeaveGroup:                   settings.WhoCanLeaveGroup,
+			WhoCanViewGroup:                    settings.WhoCanViewGroup,
+			MessageModerationLevel:             settings.MessageModerationLevel,
+			SpamModerationLevel:                settings.SpamModerationLevel,
+			DefaultMessageDenyNotificationText: stringPtr(settings.DefaultMessageDenyNotificationText),
+			ReplyTo:                            settings.ReplyTo,
+			CustomReplyTo:                      stringPtr(settings.CustomReplyTo),
+			DefaultSender:                      settings.DefaultSender,
+			CustomFooterText:                   stringPtr(settings.CustomFooterText),
+			PrimaryLanguage:                    stringPtr(settings.PrimaryLanguage),
+		},
+		Members: []Member{},
 	}
 
-	isArchived, err := strconv.ParseBool(settings.IsArchived)
-	if err != nil {
-		return Group{}, fmt.Errorf("invalid 'IsArchived' value: %v", err)
+	for _, alias := range aliases {
+		group.Aliases = append(group.Aliases, CanonicalEmail(alias))
 	}
 
-	group := Group{
-		Name:                 gsuiteGroup.Name,
-		Email:                gsuiteGroup.Email,
-		Description:          gsuiteGroup.Description,
-		WhoCanContactOwner:   settings.WhoCanContactOwner,
-		WhoCanViewMembership: settings.WhoCanViewMembership,
-		WhoCanApproveMembers: settings.WhoCanApproveMembers,
-		WhoCanPostMessage:    settings.WhoCanPostMessage,
-		WhoCanJoin:           settings.WhoCanJoin,
-		AllowExternalMembers: allowExternalMembers,
-		IsArchived:           isArchived,
-		Members:              []Member{},
+	boolSettings := []struct {
+		name   string
+		value  string
+		target **bool
+	}{
+		{"AllowExternalMembers", settings.AllowExternalMembers, &group.AllowExternalMembers},
+		{"AllowWebPosting", settings.AllowWebPosting, &group.AllowWebPosting},
+		{"IsArchived", settings.IsArchived, &group.IsArchived},
+		{"ArchiveOnly", settings.ArchiveOnly, &group.ArchiveOnly},
+		{"SendMessageDenyNotification", settings.SendMessageDenyNotification, &group.SendMessageDenyNotification},
+		{"MembersCanPostAsTheGroup", settings.MembersCanPostAsTheGroup, &group.MembersCanPostAsTheGroup},
+		{"IncludeCustomFooter", settings.IncludeCustomFooter, &group.IncludeCustomFooter},
+		{"IncludeInGlobalAddressList", settings.IncludeInGlobalAddressList, &group.IncludeInGlobalAddressList},
+		{"EnableCollaborativeInbox", settings.EnableCollaborativeInbox, &group.EnableCollaborativeInbox},
+		{"FavoriteRepliesOnTop", settings.FavoriteRepliesOnTop, &group.FavoriteRepliesOnTop},
+	}
+
+	for _, setting := range boolSettings {
+		value, err := parseBoolSetting(setting.value)
+		if err != nil {
+			return Group{}, fmt.Errorf("invalid '%s' value: %v", setting.name, err)
+		}
+
+		*setting.target = value
 	}
 
 	for _, m := range members {
@@ -317,10 +401,36 @@ func ToConfigGroup(gsuiteGroup *directoryv1.Group, settings *groupssettingsv1.Gr
 	return group, nil
 }
 
+// formatBoolSetting returns the string representation that the Groups
+// Settings API uses for booleans; unset values are omitted.
+func formatBoolSetting(value *bool) string {
+	if value == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(*value)
+}
+
+// parseBoolSetting is the inverse of formatBoolSetting.
+func parseBoolSetting(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
 func ToGSuiteGroupMember(member *Member, gsuiteMember *directoryv1.Member) *directoryv1.Member {
 	result := &directoryv1.Member{
-		Email: member.Email,
-		Role:  member.Role,
+		Email:            member.Email,
+		Role:             member.Role,
+		Type:             member.Type,
+		DeliverySettings: member.Delivery,
 	}
 
 	if gsuiteMember != nil {
@@ -333,8 +443,10 @@ func ToGSuiteGroupMember(member *Member, gsuiteMember *directoryv1.Member) *dire
 
 func ToConfigGroupMember(gsuiteMember *directoryv1.Member) Member {
 	return Member{
-		Email: gsuiteMember.Email,
-		Role:  gsuiteMember.Role,
+		Email:    CanonicalEmail(gsuiteMember.Email),
+		Role:     gsuiteMember.Role,
+		Type:     gsuiteMember.Type,
+		Delivery: gsuiteMember.DeliverySettings,
 	}
 }
 
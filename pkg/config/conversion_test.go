@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupConversion(t *testing.T) {
+	settings := DefaultGroupSettings
+	settings.WhoCanJoin = GroupOptionAllInDomainCanJoin
+	settings.WhoCanModerateContent = GroupOptionOwnersOnly
+	settings.AllowWebPosting = boolPtr(false)
+	settings.ArchiveOnly = boolPtr(true)
+	settings.ReplyTo = GroupOptionReplyToCustom
+	settings.CustomReplyTo = stringPtr("support@example.com")
+	settings.IncludeCustomFooter = boolPtr(true)
+	settings.CustomFooterText = stringPtr("Sent via the team list")
+	settings.PrimaryLanguage = stringPtr("de")
+
+	group := Group{
+		Name:          "Team",
+		Email:         "team@example.com",
+		Description:   "The team",
+		Aliases:       []string{"crew@example.com"},
+		GroupSettings: settings,
+		Members:       []Member{},
+	}
+
+	// aliases are fetched separately and not part of the group
+	gsuiteGroup, gsuiteSettings := ToGSuiteGroup(&group)
+
+	converted, err := ToConfigGroup(gsuiteGroup, gsuiteSettings, nil, group.Aliases)
+	if err != nil {
+		t.Fatalf("Failed to convert group: %v", err)
+	}
+
+	if !reflect.DeepEqual(converted, group) {
+		t.Fatalf("Expected\n%+v\nbut got\n%+v", group, converted)
+	}
+
+	gsuiteSettings.AllowWebPosting = "maybe"
+	if _, err := ToConfigGroup(gsuiteGroup, gsuiteSettings, nil, nil); err == nil {
+		t.Fatal("Expected an invalid boolean setting to be rejected")
+	}
+}
@@ -17,16 +17,30 @@ limitations under the License.
 package config
 
 import (
+	"reflect"
 	"sort"
 	"strings"
 )
 
 func (c *Config) DefaultUsers() error {
+	if c.UserRemovalPolicy.Policy == "" {
+		c.UserRemovalPolicy.Policy = UserRemovalDefault
+	}
+
+	if c.DataTransfer != nil {
+		c.DataTransfer.FallbackOwner = c.canonicalEmail(c.DataTransfer.FallbackOwner)
+	}
+
 	for idx, user := range c.Users {
 		if user.OrgUnitPath == "" {
 			user.OrgUnitPath = "/"
 		}
 
+		user.PrimaryEmail = c.canonicalEmail(user.PrimaryEmail)
+		user.Employee.ManagerEmail = c.canonicalEmail(user.Employee.ManagerEmail)
+		user.Aliases = c.canonicalEmails(user.Aliases)
+		user.PreviousEmails = c.canonicalEmails(user.PreviousEmails)
+
 		c.Users[idx] = user
 	}
 
@@ -34,6 +48,10 @@ func (c *Config) DefaultUsers() error {
 }
 
 func (c *Config) UndefaultUsers() error {
+	if c.UserRemovalPolicy.Policy == UserRemovalDefault {
+		c.UserRemovalPolicy.Policy = ""
+	}
+
 	for idx, user := range c.Users {
 		if user.OrgUnitPath == "/" {
 			user.OrgUnitPath = ""
@@ -45,40 +63,86 @@ func (c *Config) UndefaultUsers() error {
 	return nil
 }
 
+// DefaultGroupSettings are the settings that GSuite uses for new groups.
+var DefaultGroupSettings = GroupSettings{
+	WhoCanContactOwner:          GroupOptionWhoCanContactOwnerDefault,
+	WhoCanViewMembership:        GroupOptionWhoCanViewMembershipDefault,
+	WhoCanApproveMembers:        GroupOptionWhoCanApproveMembersDefault,
+	WhoCanPostMessage:           GroupOptionWhoCanPostMessageDefault,
+	WhoCanJoin:                  GroupOptionWhoCanJoinDefault,
+	WhoCanModerateMembers:       GroupOptionWhoCanModerateMembersDefault,
+	WhoCanModerateContent:       GroupOptionWhoCanModerateContentDefault,
+	WhoCanAssistContent:         GroupOptionWhoCanAssistContentDefault,
+	WhoCanDiscoverGroup:         GroupOptionWhoCanDiscoverGroupDefault,
+	WhoCanLeaveGroup:            GroupOptionWhoCanLeaveGroupDefault,
+	WhoCanViewGroup:             GroupOptionWhoCanViewGroupDefault,
+	AllowExternalMembers:        boolPtr(GroupOptionAllowExternalMembersDefault),
+	AllowWebPosting:             boolPtr(GroupOptionAllowWebPostingDefault),
+	IsArchived:                  boolPtr(GroupOptionIsArchivedDefault),
+	ArchiveOnly:                 boolPtr(GroupOptionArchiveOnlyDefault),
+	MessageModerationLevel:      GroupOptionMessageModerationLevelDefault,
+	SpamModerationLevel:         GroupOptionSpamModerationLevelDefault,
+	SendMessageDenyNotification: boolPtr(GroupOptionSendMessageDenyNotificationDefault),
+	ReplyTo:                     GroupOptionReplyToDefault,
+	DefaultSender:               GroupOptionDefaultSenderDefault,
+	MembersCanPostAsTheGroup:    boolPtr(GroupOptionMembersCanPostAsTheGroupDefault),
+	IncludeCustomFooter:         boolPtr(GroupOptionIncludeCustomFooterDefault),
+	IncludeInGlobalAddressList:  boolPtr(GroupOptionIncludeInGlobalAddressListDefault),
+	EnableCollaborativeInbox:    boolPtr(GroupOptionEnableCollaborativeInboxDefault),
+	FavoriteRepliesOnTop:        boolPtr(GroupOptionFavoriteRepliesOnTopDefault),
+
+	// free-text settings
+	DefaultMessageDenyNotificationText: stringPtr(""),
+	CustomReplyTo:                      stringPtr(""),
+	CustomFooterText:                   stringPtr(""),
+	PrimaryLanguage:                    stringPtr(""),
+}
+
+// ManagedGroupSettings are the settings that are managed for all groups
+// under the managed policy, with GSuite's defaults. These are the settings
+// GMan has managed from the start; all others are only managed if a group
+// or the groupDefaults configure them.
+var ManagedGroupSettings = GroupSettings{
+	WhoCanContactOwner:   DefaultGroupSettings.WhoCanContactOwner,
+	WhoCanViewMembership: DefaultGroupSettings.WhoCanViewMembership,
+	WhoCanApproveMembers: DefaultGroupSettings.WhoCanApproveMembers,
+	WhoCanPostMessage:    DefaultGroupSettings.WhoCanPostMessage,
+	WhoCanJoin:           DefaultGroupSettings.WhoCanJoin,
+	AllowExternalMembers: DefaultGroupSettings.AllowExternalMembers,
+	IsArchived:           DefaultGroupSettings.IsArchived,
+}
+
 func (c *Config) DefaultGroups() error {
-	for idx, group := range c.Groups {
-		if group.WhoCanJoin == "" {
-			group.WhoCanJoin = GroupOptionWhoCanJoinDefault
-		}
+	if c.GroupSettingsPolicy == "" {
+		c.GroupSettingsPolicy = GroupSettingsDefault
+	}
 
-		if group.WhoCanPostMessage == "" {
-			group.WhoCanPostMessage = GroupOptionWhoCanPostMessageDefault
-		}
+	if c.GroupDefaults != nil {
+		*c.GroupDefaults = c.normalizeGroupSettings(*c.GroupDefaults)
+	}
 
-		if group.WhoCanApproveMembers == "" {
-			group.WhoCanApproveMembers = GroupOptionWhoCanApproveMembersDefault
-		}
+	defaults := c.groupSettingsDefaults(c.GroupSettingsPolicy)
 
-		if group.WhoCanContactOwner == "" {
-			group.WhoCanContactOwner = GroupOptionWhoCanContactOwnerDefault
-		}
+	for idx, group := range c.Groups {
+		group.GroupSettings = c.normalizeGroupSettings(group.GroupSettings).withDefaults(defaults)
 
-		if group.WhoCanViewMembership == "" {
-			group.WhoCanViewMembership = GroupOptionWhoCanViewMembershipDefault
+		group.Email = c.canonicalEmail(group.Email)
+		if group.MembershipMode == "" {
+			group.MembershipMode = MembershipModeDefault
 		}
 
-		group.WhoCanJoin = strings.ToUpper(group.WhoCanJoin)
-		group.WhoCanPostMessage = strings.ToUpper(group.WhoCanPostMessage)
-		group.WhoCanApproveMembers = strings.ToUpper(group.WhoCanApproveMembers)
-		group.WhoCanContactOwner = strings.ToUpper(group.WhoCanContactOwner)
-		group.WhoCanViewMembership = strings.ToUpper(group.WhoCanViewMembership)
+		group.Aliases = c.canonicalEmails(group.Aliases)
+		group.PreviousEmails = c.canonicalEmails(group.PreviousEmails)
 
 		for n, member := range group.Members {
 			if member.Role == "" {
 				member.Role = MemberRoleMember
 			}
 
+			member.Email = c.canonicalEmail(member.Email)
 			member.Role = strings.ToUpper(member.Role)
+			member.Type = strings.ToUpper(member.Type)
+			member.Delivery = strings.ToUpper(member.Delivery)
 			group.Members[n] = member
 		}
 
@@ -89,25 +153,19 @@ func (c *Config) DefaultGroups() error {
 }
 
 func (c *Config) UndefaultGroups() error {
-	for idx, group := range c.Groups {
-		if group.WhoCanJoin == GroupOptionWhoCanJoinDefault {
-			group.WhoCanJoin = ""
-		}
+	// exports only contain settings that differ from the defaults, even
+	// those that are left unmanaged instead of being defaulted
+	defaults := c.groupSettingsDefaults(GroupSettingsExplicit).withDefaults(DefaultGroupSettings)
 
-		if group.WhoCanPostMessage == GroupOptionWhoCanPostMessageDefault {
-			group.WhoCanPostMessage = ""
-		}
-
-		if group.WhoCanApproveMembers == GroupOptionWhoCanApproveMembersDefault {
-			group.WhoCanApproveMembers = ""
-		}
+	if c.GroupSettingsPolicy == GroupSettingsDefault {
+		c.GroupSettingsPolicy = ""
+	}
 
-		if group.WhoCanContactOwner == GroupOptionWhoCanContactOwnerDefault {
-			group.WhoCanContactOwner = ""
-		}
+	for idx, group := range c.Groups {
+		group.GroupSettings = group.GroupSettings.withoutDefaults(defaults)
 
-		if group.WhoCanViewMembership == GroupOptionWhoCanViewMembershipDefault {
-			group.WhoCanViewMembership = ""
+		if group.MembershipMode == MembershipModeDefault {
+			group.MembershipMode = ""
 		}
 
 		for n, member := range group.Members {
@@ -115,6 +173,14 @@ func (c *Config) UndefaultGroups() error {
 				member.Role = ""
 			}
 
+			if member.Type == MemberTypeUser {
+				member.Type = ""
+			}
+
+			if member.Delivery == MemberDeliveryDefault {
+				member.Delivery = ""
+			}
+
 			group.Members[n] = member
 		}
 
@@ -124,6 +190,116 @@ func (c *Config) UndefaultGroups() error {
 	return nil
 }
 
+// groupSettingsDefaults returns the settings that groups get if they do
+// not configure them: the configured groupDefaults and, for the managed
+// policy, GSuite's defaults for the remaining ManagedGroupSettings.
+func (c *Config) groupSettingsDefaults(policy string) GroupSettings {
+	defaults := GroupSettings{}
+	if c.GroupDefaults != nil {
+		defaults = *c.GroupDefaults
+	}
+
+	if policy == GroupSettingsManaged {
+		defaults = defaults.withDefaults(ManagedGroupSettings)
+	}
+
+	return defaults
+}
+
+// normalizeGroupSettings uppercases all enum settings and canonicalizes
+// the custom reply-to address.
+func (c *Config) normalizeGroupSettings(settings GroupSettings) GroupSettings {
+	settings.WhoCanJoin = strings.ToUpper(settings.WhoCanJoin)
+	settings.WhoCanPostMessage = strings.ToUpper(settings.WhoCanPostMessage)
+	settings.WhoCanApproveMembers = strings.ToUpper(settings.WhoCanApproveMembers)
+	settings.WhoCanContactOwner = strings.ToUpper(settings.WhoCanContactOwner)
+	settings.WhoCanViewMembership = strings.ToUpper(settings.WhoCanViewMembership)
+	settings.WhoCanModerateMembers = strings.ToUpper(settings.WhoCanModerateMembers)
+	settings.WhoCanModerateContent = strings.ToUpper(settings.WhoCanModerateContent)
+	settings.WhoCanAssistContent = strings.ToUpper(settings.WhoCanAssistContent)
+	settings.WhoCanDiscoverGroup = strings.ToUpper(settings.WhoCanDiscoverGroup)
+	settings.WhoCanLeaveGroup = strings.ToUpper(settings.WhoCanLeaveGroup)
+	settings.WhoCanViewGroup = strings.ToUpper(settings.WhoCanViewGroup)
+	settings.MessageModerationLevel = strings.ToUpper(settings.MessageModerationLevel)
+	settings.SpamModerationLevel = strings.ToUpper(settings.SpamModerationLevel)
+	settings.ReplyTo = strings.ToUpper(settings.ReplyTo)
+	settings.DefaultSender = strings.ToUpper(settings.DefaultSender)
+
+	if settings.CustomReplyTo != nil {
+		settings.CustomReplyTo = stringPtr(c.canonicalEmail(*settings.CustomReplyTo))
+	}
+
+	return settings
+}
+
+// OnlyConfigured returns a copy of the settings without all those that
+// are unset in configured, which are not managed by GMan.
+func (s GroupSettings) OnlyConfigured(configured GroupSettings) GroupSettings {
+	settings := reflect.ValueOf(&s).Elem()
+	configuredValues := reflect.ValueOf(configured)
+
+	for i := 0; i < settings.NumField(); i++ {
+		if configuredValues.Field(i).IsZero() {
+			field := settings.Field(i)
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return s
+}
+
+// withDefaults returns a copy of the settings with all unset settings
+// taken from defaults.
+func (s GroupSettings) withDefaults(defaults GroupSettings) GroupSettings {
+	settings := reflect.ValueOf(&s).Elem()
+	defaultValues := reflect.ValueOf(defaults)
+
+	for i := 0; i < settings.NumField(); i++ {
+		field := settings.Field(i)
+		defaultValue := defaultValues.Field(i)
+
+		if !field.IsZero() || defaultValue.IsZero() {
+			continue
+		}
+
+		// do not share pointers between groups
+		if defaultValue.Kind() == reflect.Ptr {
+			copied := reflect.New(defaultValue.Elem().Type())
+			copied.Elem().Set(defaultValue.Elem())
+			defaultValue = copied
+		}
+
+		field.Set(defaultValue)
+	}
+
+	return s
+}
+
+// withoutDefaults returns a copy of the settings with all settings unset
+// that are equal to their default.
+func (s GroupSettings) withoutDefaults(defaults GroupSettings) GroupSettings {
+	settings := reflect.ValueOf(&s).Elem()
+	defaultValues := reflect.ValueOf(defaults)
+
+	for i := 0; i < settings.NumField(); i++ {
+		field := settings.Field(i)
+
+		if reflect.DeepEqual(field.Interface(), defaultValues.Field(i).Interface()) {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return s
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
 func (c *Config) DefaultOrgUnits() error {
 	for idx, orgUnit := range c.OrgUnits {
 		if orgUnit.ParentOrgUnitPath == "" {
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// CanonicalEmail returns the form in which GSuite stores email addresses.
+// Configured and live emails are always compared in this form.
+func CanonicalEmail(email string) string {
+	return strings.ToLower(email)
+}
+
+// canonicalEmail canonicalizes an email from the configuration file and
+// remembers it if it was not in canonical form already.
+func (c *Config) canonicalEmail(email string) string {
+	canonical := CanonicalEmail(email)
+	if canonical != email {
+		c.mixedCaseEmails = append(c.mixedCaseEmails, email)
+	}
+
+	return canonical
+}
+
+func (c *Config) canonicalEmails(emails []string) []string {
+	for i, email := range emails {
+		emails[i] = c.canonicalEmail(email)
+	}
+
+	return emails
+}
+
+// MixedCaseEmails returns the emails that were written in mixed case in
+// the configuration file. They work, but can be confusing to read, as
+// GSuite and GMan ignore their case.
+func (c *Config) MixedCaseEmails() []string {
+	return sets.NewString(c.mixedCaseEmails...).List()
+}
+
+// emailUse is a configured user or group that uses an email address,
+// either as its primary email or as an alias.
+type emailUse struct {
+	filename string
+	kind     string
+	owner    string
+	alias    bool
+}
+
+func (u emailUse) String() string {
+	if u.alias {
+		return fmt.Sprintf("alias of %s %s (%s)", u.kind, u.owner, u.filename)
+	}
+
+	return fmt.Sprintf("%s %s (%s)", u.kind, u.owner, u.filename)
+}
+
+// emailIndex maps lowercased email addresses to all of their uses.
+type emailIndex map[string][]emailUse
+
+func (i emailIndex) add(email string, use emailUse) {
+	if email == "" {
+		return
+	}
+
+	key := CanonicalEmail(email)
+	i[key] = append(i[key], use)
+}
+
+// newEmailIndex indexes the primary emails and aliases of all configured
+// users and groups. Both configs are optional.
+func newEmailIndex(users File, groups File) emailIndex {
+	index := emailIndex{}
+
+	if users.Config != nil {
+		for _, user := range users.Users {
+			index.add(user.PrimaryEmail, emailUse{filename: users.Filename, kind: "user", owner: user.PrimaryEmail})
+
+			for _, alias := range user.Aliases {
+				index.add(alias, emailUse{filename: users.Filename, kind: "user", owner: user.PrimaryEmail, alias: true})
+			}
+		}
+	}
+
+	if groups.Config != nil {
+		for _, group := range groups.Groups {
+			index.add(group.Email, emailUse{filename: groups.Filename, kind: "group", owner: group.Email})
+
+			for _, alias := range group.Aliases {
+				index.add(alias, emailUse{filename: groups.Filename, kind: "group", owner: group.Email, alias: true})
+			}
+		}
+	}
+
+	return index
+}
+
+// ValidateEmails checks that no email address is used more than once
+// across all users, groups and their aliases. GSuite treats addresses
+// case-insensitively, so they are compared that way, too.
+func ValidateEmails(users File, groups File) []error {
+	var allErrors []error
+
+	index := newEmailIndex(users, groups)
+
+	emails := make([]string, 0, len(index))
+	for email := range index {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	for _, email := range emails {
+		uses := index[email]
+		if len(uses) < 2 {
+			continue
+		}
+
+		descriptions := make([]string, 0, len(uses))
+		for _, use := range uses {
+			descriptions = append(descriptions, use.String())
+		}
+
+		allErrors = append(allErrors, fmt.Errorf("[email: %s] used %d times: %s", email, len(uses), strings.Join(descriptions, ", ")))
+	}
+
+	return allErrors
+}
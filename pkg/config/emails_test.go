@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmails(t *testing.T) {
+	users := File{
+		Filename: "users.yaml",
+		Config: &Config{
+			Users: []User{
+				{PrimaryEmail: "jane@example.com", Aliases: []string{"jd@example.com", "team@example.com"}},
+				{PrimaryEmail: "bob@example.com"},
+				{PrimaryEmail: "Bob@Example.com"},
+			},
+		},
+	}
+
+	groups := File{
+		Filename: "groups.yaml",
+		Config: &Config{
+			Groups: []Group{
+				{Email: "team@example.com"},
+				{Email: "all@example.com", Aliases: []string{"jd@example.com"}},
+			},
+		},
+	}
+
+	expected := []string{
+		"[email: bob@example.com] used 2 times: user bob@example.com (users.yaml), user Bob@Example.com (users.yaml)",
+		"[email: jd@example.com] used 2 times: alias of user jane@example.com (users.yaml), alias of group all@example.com (groups.yaml)",
+		"[email: team@example.com] used 2 times: alias of user jane@example.com (users.yaml), group team@example.com (groups.yaml)",
+	}
+
+	errs := []string{}
+	for _, err := range ValidateEmails(users, groups) {
+		errs = append(errs, err.Error())
+	}
+
+	if strings.Join(errs, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected errors\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(errs, "\n"))
+	}
+
+	if errs := ValidateEmails(File{}, groups); len(errs) > 0 {
+		t.Fatalf("Expected groups without users to be valid, but got %v", errs)
+	}
+}
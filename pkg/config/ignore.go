@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Ignore selects resources that are not managed by GMan at all: they are
+// neither updated nor deleted during a sync and left out of exports.
+// A resource is ignored if it matches any of the selectors of its kind.
+type Ignore struct {
+	Users  []Selector `yaml:"users,omitempty" json:"users,omitempty"`
+	Groups []Selector `yaml:"groups,omitempty" json:"groups,omitempty"`
+
+	// OrgUnits are org unit paths; each ignores the org unit and all of
+	// its children.
+	OrgUnits []string `yaml:"orgUnits,omitempty" json:"orgUnits,omitempty"`
+}
+
+// Selector matches users or groups. All of its given fields must match.
+type Selector struct {
+	// Email is a glob pattern (see path.Match), matched case-insensitively.
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+
+	// EmailRegex is a regular expression, matched case-insensitively.
+	EmailRegex string `yaml:"emailRegex,omitempty" json:"emailRegex,omitempty"`
+
+	// OrgUnit matches users in the org unit or any of its children.
+	OrgUnit string `yaml:"orgUnit,omitempty" json:"orgUnit,omitempty"`
+
+	// NamePrefix matches groups whose name starts with the prefix.
+	NamePrefix string `yaml:"namePrefix,omitempty" json:"namePrefix,omitempty"`
+}
+
+func (s *Selector) empty() bool {
+	return s.Email == "" && s.EmailRegex == "" && s.OrgUnit == "" && s.NamePrefix == ""
+}
+
+func (s *Selector) String() string {
+	fields := []string{}
+
+	if s.Email != "" {
+		fields = append(fields, "email="+s.Email)
+	}
+
+	if s.EmailRegex != "" {
+		fields = append(fields, "emailRegex="+s.EmailRegex)
+	}
+
+	if s.OrgUnit != "" {
+		fields = append(fields, "orgUnit="+s.OrgUnit)
+	}
+
+	if s.NamePrefix != "" {
+		fields = append(fields, "namePrefix="+s.NamePrefix)
+	}
+
+	return strings.Join(fields, ",")
+}
+
+func (s *Selector) matches(email string, name string, orgUnitPath string) bool {
+	if s.empty() {
+		return false
+	}
+
+	if s.Email != "" && !matchesAny([]string{s.Email}, email) {
+		return false
+	}
+
+	if s.EmailRegex != "" {
+		if matched, _ := regexp.MatchString("(?i)"+s.EmailRegex, email); !matched {
+			return false
+		}
+	}
+
+	if s.OrgUnit != "" && !inOrgUnitTree(s.OrgUnit, orgUnitPath) {
+		return false
+	}
+
+	if s.NamePrefix != "" && !strings.HasPrefix(name, s.NamePrefix) {
+		return false
+	}
+
+	return true
+}
+
+// validate checks that the selector is well-formed and only uses fields
+// that apply to the given kind of resource.
+func (s *Selector) validate(kind string) error {
+	if s.empty() {
+		return fmt.Errorf("[ignore %s] selector must not be empty", kind)
+	}
+
+	if s.Email != "" {
+		if _, err := path.Match(s.Email, ""); err != nil {
+			return fmt.Errorf("[ignore %s: %s] invalid email pattern: %v", kind, s, err)
+		}
+	}
+
+	if s.EmailRegex != "" {
+		if _, err := regexp.Compile(s.EmailRegex); err != nil {
+			return fmt.Errorf("[ignore %s: %s] invalid email regex: %v", kind, s, err)
+		}
+	}
+
+	if s.OrgUnit != "" && kind != "users" {
+		return fmt.Errorf("[ignore %s: %s] orgUnit can only be used for users", kind, s)
+	}
+
+	if s.OrgUnit != "" && !strings.HasPrefix(s.OrgUnit, "/") {
+		return fmt.Errorf("[ignore %s: %s] org unit path must start with a slash", kind, s)
+	}
+
+	if s.NamePrefix != "" && kind != "groups" {
+		return fmt.Errorf("[ignore %s: %s] namePrefix can only be used for groups", kind, s)
+	}
+
+	return nil
+}
+
+func (i *Ignore) IsUserIgnored(email string, orgUnitPath string) bool {
+	for _, selector := range i.Users {
+		if selector.matches(email, "", orgUnitPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *Ignore) IsGroupIgnored(email string, name string) bool {
+	for _, selector := range i.Groups {
+		if selector.matches(email, name, "") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *Ignore) IsOrgUnitIgnored(orgUnitPath string) bool {
+	for _, tree := range i.OrgUnits {
+		if inOrgUnitTree(tree, orgUnitPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inOrgUnitTree returns true if orgUnitPath is the root or a descendant
+// of the org unit tree.
+func inOrgUnitTree(tree string, orgUnitPath string) bool {
+	tree = strings.ToLower(strings.TrimSuffix(tree, "/"))
+	orgUnitPath = strings.ToLower(orgUnitPath)
+
+	return tree == "" || orgUnitPath == tree || strings.HasPrefix(orgUnitPath, tree+"/")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	ignore := Ignore{
+		Users: []Selector{
+			{Email: "bot-*@example.com"},
+			{EmailRegex: `^svc\.`, OrgUnit: "/Services"},
+		},
+		Groups: []Selector{
+			{NamePrefix: "[Auto]"},
+		},
+		OrgUnits: []string{"/External/"},
+	}
+
+	users := []struct {
+		email       string
+		orgUnitPath string
+		expected    bool
+	}{
+		{email: "bot-ci@example.com", orgUnitPath: "/", expected: true},
+		{email: "Bot-CI@Example.com", orgUnitPath: "/", expected: true},
+		{email: "robot@example.com", orgUnitPath: "/", expected: false},
+		{email: "svc.backup@example.com", orgUnitPath: "/Services/Backup", expected: true},
+		{email: "svc.backup@example.com", orgUnitPath: "/", expected: false},
+		{email: "jane@example.com", orgUnitPath: "/Services", expected: false},
+	}
+
+	for _, user := range users {
+		if ignored := ignore.IsUserIgnored(user.email, user.orgUnitPath); ignored != user.expected {
+			t.Errorf("Expected IsUserIgnored(%q, %q) to be %v, but got %v", user.email, user.orgUnitPath, user.expected, ignored)
+		}
+	}
+
+	if !ignore.IsGroupIgnored("alerts@example.com", "[Auto] Alerts") {
+		t.Error("Expected group with name prefix to be ignored")
+	}
+
+	if ignore.IsGroupIgnored("team@example.com", "Team [Auto]") {
+		t.Error("Expected group without name prefix not to be ignored")
+	}
+
+	orgUnits := map[string]bool{
+		"/External":          true,
+		"/external/Partners": true,
+		"/Externals":         false,
+		"/":                  false,
+	}
+
+	for orgUnitPath, expected := range orgUnits {
+		if ignored := ignore.IsOrgUnitIgnored(orgUnitPath); ignored != expected {
+			t.Errorf("Expected IsOrgUnitIgnored(%q) to be %v, but got %v", orgUnitPath, expected, ignored)
+		}
+	}
+}
+
+func TestIgnoreValidation(t *testing.T) {
+	testcases := []struct {
+		kind     string
+		selector Selector
+		valid    bool
+	}{
+		{kind: "users", selector: Selector{Email: "*@example.com", OrgUnit: "/Bots"}, valid: true},
+		{kind: "groups", selector: Selector{EmailRegex: "^auto-", NamePrefix: "[Auto]"}, valid: true},
+		{kind: "users", selector: Selector{}},
+		{kind: "users", selector: Selector{Email: "[invalid"}},
+		{kind: "users", selector: Selector{EmailRegex: "(invalid"}},
+		{kind: "users", selector: Selector{OrgUnit: "Bots"}},
+		{kind: "users", selector: Selector{NamePrefix: "[Auto]"}},
+		{kind: "groups", selector: Selector{OrgUnit: "/Bots"}},
+	}
+
+	for _, tc := range testcases {
+		err := tc.selector.validate(tc.kind)
+		if tc.valid && err != nil {
+			t.Errorf("Expected %s selector %s to be valid, but got %v", tc.kind, &tc.selector, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("Expected %s selector %s to be invalid", tc.kind, &tc.selector)
+		}
+	}
+}
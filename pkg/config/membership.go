@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"time"
+)
+
+// MemberDateLayout is the format of the since and expires dates of members.
+const MemberDateLayout = "2006-01-02"
+
+// Active returns true if the membership has started and not yet expired
+// at the given time. Invalid dates are rejected by the validation and
+// treated as unset here.
+func (m Member) Active(now time.Time) bool {
+	if m.Pending(now) {
+		return false
+	}
+
+	// memberships end with the last day
+	if expires, err := time.Parse(MemberDateLayout, m.Expires); err == nil && !now.Before(expires.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	return true
+}
+
+// Pending returns true if the membership starts after the given time.
+func (m Member) Pending(now time.Time) bool {
+	since, err := time.Parse(MemberDateLayout, m.Since)
+
+	return err == nil && now.Before(since)
+}
+
+// Expiration is a membership that expires at some point.
+type Expiration struct {
+	Group   string
+	Member  Member
+	Expires time.Time
+}
+
+// Expired returns true if the membership has already expired at the given time.
+func (e Expiration) Expired(now time.Time) bool {
+	return !now.Before(e.Expires.AddDate(0, 0, 1))
+}
+
+// Expirations returns all memberships with an expiry date that expire
+// before the given time, both past and upcoming ones, ordered by date.
+func (c *Config) Expirations(before time.Time) []Expiration {
+	expirations := []Expiration{}
+
+	for _, group := range c.Groups {
+		for _, member := range group.Members {
+			expires, err := time.Parse(MemberDateLayout, member.Expires)
+			if err != nil || !expires.Before(before) {
+				continue
+			}
+
+			expirations = append(expirations, Expiration{
+				Group:   group.Email,
+				Member:  member,
+				Expires: expires,
+			})
+		}
+	}
+
+	sort.SliceStable(expirations, func(i, j int) bool {
+		return expirations[i].Expires.Before(expirations[j].Expires)
+	})
+
+	return expirations
+}
+
+// EffectiveMember is a member of a group, either directly or through one
+// or more nested groups.
+type EffectiveMember struct {
+	Member
+
+	// Via are the nested groups that the member belongs to the group
+	// through, outermost first; empty for direct members.
+	Via []string
+}
+
+// EffectiveMembers returns the transitive members of the configured group
+// with the given email. Configured nested groups are replaced by their own
+// members, other nested groups are returned as they are. Members that are
+// reachable in multiple ways are only returned once, via the shortest path.
+// Memberships that are not active today are left out.
+func (c *Config) EffectiveMembers(email string) []EffectiveMember {
+	groups := map[string]*Group{}
+	for i, group := range c.Groups {
+		groups[group.Email] = &c.Groups[i]
+	}
+
+	members := []EffectiveMember{}
+	seen := map[string]bool{email: true}
+	now := time.Now()
+
+	// breadth-first, so that the shortest path to each member wins
+	queue := []EffectiveMember{{Member: Member{Email: email}}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		via := current.Via
+		if current.Email != email {
+			via = append(append([]string{}, current.Via...), current.Email)
+		}
+
+		for _, member := range groups[current.Email].Members {
+			if !member.Active(now) {
+				continue
+			}
+
+			key := member.Type + ":" + member.Email
+			if member.Email != "" {
+				key = member.Email
+			}
+
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			effective := EffectiveMember{Member: member, Via: via}
+
+			if _, nested := groups[member.Email]; nested {
+				queue = append(queue, effective)
+			} else {
+				members = append(members, effective)
+			}
+		}
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Email < members[j].Email
+	})
+
+	return members
+}
+
+// membershipCycles returns cycles of groups that are (indirectly) members
+// of themselves, at least one for every set of groups involved in cycles.
+// Each cycle starts and ends with the same group.
+func membershipCycles(groups []Group) [][]string {
+	nested := map[string][]string{}
+	for _, group := range groups {
+		nested[group.Email] = nil
+	}
+
+	for _, group := range groups {
+		for _, member := range group.Members {
+			if _, configured := nested[member.Email]; configured {
+				nested[group.Email] = append(nested[group.Email], member.Email)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	cycles := [][]string{}
+	state := map[string]int{}
+	stack := []string{}
+
+	var visit func(email string)
+	visit = func(email string) {
+		state[email] = visiting
+		stack = append(stack, email)
+
+		for _, member := range nested[email] {
+			switch state[member] {
+			case unvisited:
+				visit(member)
+			case visiting:
+				// the stack from the member onwards forms a cycle
+				for i := range stack {
+					if stack[i] == member {
+						cycle := append(append([]string{}, stack[i:]...), member)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[email] = visited
+	}
+
+	for _, group := range groups {
+		if state[group.Email] == unvisited {
+			visit(group.Email)
+		}
+	}
+
+	return cycles
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEffectiveMembers(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Email: "all@example.com",
+				Members: []Member{
+					{Email: "engineering@example.com", Type: MemberTypeGroup},
+					{Email: "sales@example.com", Type: MemberTypeGroup},
+					{Email: "partners@example.org", Type: MemberTypeGroup},
+				},
+			},
+			{
+				Email: "engineering@example.com",
+				Members: []Member{
+					{Email: "jane@example.com"},
+					{Email: "platform@example.com", Type: MemberTypeGroup},
+				},
+			},
+			{
+				Email: "platform@example.com",
+				Members: []Member{
+					{Email: "bob@example.com"},
+					{Email: "jane@example.com"},
+				},
+			},
+			{
+				Email: "sales@example.com",
+				Members: []Member{
+					{Email: "max@example.com"},
+				},
+			},
+		},
+	}
+
+	expected := []string{
+		"bob@example.com via [engineering@example.com platform@example.com]",
+		"jane@example.com via [engineering@example.com]",
+		"max@example.com via [sales@example.com]",
+		"partners@example.org via []",
+	}
+
+	members := []string{}
+	for _, member := range cfg.EffectiveMembers("all@example.com") {
+		members = append(members, fmt.Sprintf("%s via %v", member.Email, member.Via))
+	}
+
+	if !reflect.DeepEqual(members, expected) {
+		t.Fatalf("Expected members\n%q\nbut got\n%q", expected, members)
+	}
+}
+
+func TestMembershipCycles(t *testing.T) {
+	groups := []Group{
+		{Email: "a@example.com", Members: []Member{{Email: "b@example.com"}}},
+		{Email: "b@example.com", Members: []Member{{Email: "c@example.com"}, {Email: "jane@example.com"}}},
+		{Email: "c@example.com", Members: []Member{{Email: "a@example.com"}}},
+		{Email: "d@example.com", Members: []Member{{Email: "a@example.com"}}},
+	}
+
+	expected := [][]string{{"a@example.com", "b@example.com", "c@example.com", "a@example.com"}}
+	if cycles := membershipCycles(groups); !reflect.DeepEqual(cycles, expected) {
+		t.Fatalf("Expected cycles %v, but got %v", expected, cycles)
+	}
+
+	if cycles := membershipCycles(groups[1:]); len(cycles) > 0 {
+		t.Fatalf("Expected no cycles, but got %v", cycles)
+	}
+}
+
+func TestMemberActive(t *testing.T) {
+	now := time.Date(2021, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		since    string
+		expires  string
+		expected bool
+	}{
+		{expected: true},
+		{since: "2021-12-15", expected: true},
+		{since: "2021-12-16", expected: false},
+		{expires: "2021-12-15", expected: true},
+		{expires: "2021-12-14", expected: false},
+		{since: "2021-12-01", expires: "2021-12-31", expected: true},
+		{since: "invalid", expires: "invalid", expected: true},
+	}
+
+	for _, tc := range testcases {
+		member := Member{Email: "jane@example.com", Since: tc.since, Expires: tc.expires}
+
+		if active := member.Active(now); active != tc.expected {
+			t.Errorf("Expected membership from %q to %q to be active=%v, but got %v", tc.since, tc.expires, tc.expected, active)
+		}
+	}
+}
+
+func TestExpirations(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Email: "team@example.com",
+				Members: []Member{
+					{Email: "jane@example.com", Expires: "2022-01-31"},
+					{Email: "bob@example.com"},
+					{Email: "max@example.com", Expires: "2021-12-01"},
+				},
+			},
+			{
+				Email: "staff@example.com",
+				Members: []Member{
+					{Email: "jane@example.com", Expires: "2021-12-20"},
+				},
+			},
+		},
+	}
+
+	now := time.Date(2021, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	expected := []string{
+		"team@example.com/max@example.com expired=true",
+		"staff@example.com/jane@example.com expired=false",
+	}
+
+	expirations := []string{}
+	for _, expiration := range cfg.Expirations(now.AddDate(0, 0, 30)) {
+		expirations = append(expirations, fmt.Sprintf("%s/%s expired=%v", expiration.Group, expiration.Member.Email, expiration.Expired(now)))
+	}
+
+	if !reflect.DeepEqual(expirations, expected) {
+		t.Fatalf("Expected expirations\n%q\nbut got\n%q", expected, expirations)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/GehirnInc/crypt/sha512_crypt"
+)
+
+const (
+	// PasswordHashFunction is the GSuite hash function of the hashes
+	// created by CryptPassword.
+	PasswordHashFunction = "crypt"
+
+	cryptSaltLength = 16
+	cryptAlphabet   = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// CryptPassword returns a salted SHA-512 crypt(3) hash of the password,
+// which GSuite accepts in place of a plaintext password.
+func CryptPassword(password string) (string, error) {
+	random := make([]byte, cryptSaltLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	salt := []byte(sha512_crypt.MagicPrefix)
+	for _, b := range random {
+		salt = append(salt, cryptAlphabet[int(b)%len(cryptAlphabet)])
+	}
+
+	return sha512_crypt.New().Generate([]byte(password), salt)
+}
+
+// PasswordMatches checks if the hash was created for the given password.
+// Besides crypt hashes, the shortened hashes created by HashPassword
+// are supported.
+func PasswordMatches(password string, hash string) bool {
+	if !strings.HasPrefix(hash, sha512_crypt.MagicPrefix) {
+		return hash == HashPassword(password)
+	}
+
+	// the crypter's Verify misreads salts shorter than 16 characters,
+	// so the hash is recomputed from the salt prefix instead
+	salt := hash[:strings.LastIndex(hash, "$")]
+
+	computed, err := sha512_crypt.New().Generate([]byte(password), []byte(salt))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1
+}
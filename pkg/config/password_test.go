@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordMatches(t *testing.T) {
+	testcases := []struct {
+		name     string
+		password string
+		hash     string
+		expected bool
+	}{
+		// reference vectors from the SHA-crypt specification
+		{
+			name:     "default rounds",
+			password: "Hello world!",
+			hash:     "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			expected: true,
+		},
+		{
+			name:     "explicit rounds",
+			password: "Hello world!",
+			hash:     "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+			expected: true,
+		},
+		{
+			name:     "explicit default rounds",
+			password: "This is just a test",
+			hash:     "$6$rounds=5000$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0",
+			expected: true,
+		},
+		{
+			name:     "long password",
+			password: "a very much longer text to encrypt.  This one even stretches over morethan one line.",
+			hash:     "$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1",
+			expected: true,
+		},
+		{
+			name:     "short salt",
+			password: "we have a short salt string but not a short password",
+			hash:     "$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0",
+			expected: true,
+		},
+		{
+			name:     "wrong password",
+			password: "Hello world?",
+			hash:     "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			expected: false,
+		},
+		{
+			name:     "malformed hash",
+			password: "Hello world!",
+			hash:     "$6$",
+			expected: false,
+		},
+		// hashes stored by older versions
+		{
+			name:     "legacy hash",
+			password: "correct horse",
+			hash:     HashPassword("correct horse"),
+			expected: true,
+		},
+		{
+			name:     "wrong password for legacy hash",
+			password: "battery staple",
+			hash:     HashPassword("correct horse"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if matches := PasswordMatches(tc.password, tc.hash); matches != tc.expected {
+				t.Fatalf("Expected %v, but got %v", tc.expected, matches)
+			}
+		})
+	}
+}
+
+func TestCryptPassword(t *testing.T) {
+	hash, err := CryptPassword("correct horse")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$6$") || strings.Contains(hash, "correct horse") {
+		t.Fatalf("Expected a SHA-512 crypt hash, but got %q", hash)
+	}
+
+	if !PasswordMatches("correct horse", hash) {
+		t.Fatalf("Expected %q to match its own password", hash)
+	}
+
+	other, err := CryptPassword("correct horse")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if other == hash {
+		t.Fatalf("Expected hashes to be salted, but got %q twice", hash)
+	}
+}
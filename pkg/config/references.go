@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// File is a configuration together with the file it was loaded from.
+type File struct {
+	*Config
+	Filename string
+}
+
+// ValidateReferences checks that the org units, users and groups refer
+// to each other consistently: users must be placed in existing org units,
+// managers and data transfer recipients must be users and group members
+// in the organization's own domains must be users or groups. Resources
+// that are protected or ignored exist outside of the configuration and
+// are valid references, too. The users and groups configs are optional.
+func ValidateReferences(orgUnits File, users File, groups File) []error {
+	var allErrors []error
+
+	if users.Config != nil {
+		allErrors = append(allErrors, validateUserReferences(orgUnits, users)...)
+	}
+
+	if users.Config != nil && groups.Config != nil {
+		allErrors = append(allErrors, validateMemberReferences(users, groups)...)
+	}
+
+	return allErrors
+}
+
+func validateUserReferences(orgUnits File, users File) []error {
+	var allErrors []error
+
+	orgUnitPaths := sets.NewString("/")
+	for _, orgUnit := range orgUnits.OrgUnits {
+		orgUnitPaths.Insert(orgUnit.OrgUnitPath())
+	}
+
+	orgUnitExists := func(orgUnitPath string) bool {
+		return orgUnitPaths.Has(orgUnitPath) || orgUnits.Protected.IsOrgUnitProtected(orgUnitPath) || orgUnits.Ignore.IsOrgUnitIgnored(orgUnitPath)
+	}
+
+	userExists := func(email string) bool {
+		return userConfigured(users.Config, email) || users.Protected.IsUserProtected(email) || users.Ignore.IsUserIgnored(email, "")
+	}
+
+	for _, user := range users.Users {
+		if user.OrgUnitPath != "" && !orgUnitExists(user.OrgUnitPath) {
+			allErrors = append(allErrors, fmt.Errorf("%s: [user: %s] org unit %s is not configured in %s", users.Filename, user.PrimaryEmail, user.OrgUnitPath, orgUnits.Filename))
+		}
+
+		if manager := user.Employee.ManagerEmail; manager != "" && !userExists(manager) {
+			allErrors = append(allErrors, fmt.Errorf("%s: [user: %s] manager %s is not a configured user", users.Filename, user.PrimaryEmail, manager))
+		}
+	}
+
+	if policy := users.UserRemovalPolicy; policy.Policy == UserRemovalMoveToOrgUnit && strings.HasPrefix(policy.OrgUnitPath, "/") && !orgUnitExists(policy.OrgUnitPath) {
+		allErrors = append(allErrors, fmt.Errorf("%s: [userRemovalPolicy] org unit %s is not configured in %s", users.Filename, policy.OrgUnitPath, orgUnits.Filename))
+	}
+
+	if users.DataTransfer != nil && users.DataTransfer.FallbackOwner != "" && !userExists(users.DataTransfer.FallbackOwner) {
+		allErrors = append(allErrors, fmt.Errorf("%s: [dataTransfer] fallback owner %s is not a configured user", users.Filename, users.DataTransfer.FallbackOwner))
+	}
+
+	return allErrors
+}
+
+func validateMemberReferences(users File, groups File) []error {
+	var allErrors []error
+
+	// addresses in these domains must belong to a user or group
+	domains := sets.NewString()
+	userEmails := sets.NewString()
+	aliasOwners := map[string]string{}
+
+	for _, user := range users.Users {
+		domains.Insert(emailDomain(user.PrimaryEmail))
+		userEmails.Insert(user.PrimaryEmail)
+
+		for _, alias := range user.Aliases {
+			aliasOwners[alias] = "user " + user.PrimaryEmail
+		}
+	}
+
+	groupEmails := sets.NewString()
+	for _, group := range groups.Groups {
+		domains.Insert(emailDomain(group.Email))
+		groupEmails.Insert(group.Email)
+
+		for _, alias := range group.Aliases {
+			aliasOwners[alias] = "group " + group.Email
+		}
+	}
+
+	for _, group := range groups.Groups {
+		for _, member := range group.Members {
+			email := member.Email
+
+			switch {
+			case email == "":
+				// CUSTOMER members have no email
+			case userEmails.Has(email), groupEmails.Has(email):
+			case aliasOwners[email] != "":
+				allErrors = append(allErrors, fmt.Errorf("%s: [group: %s] member %s is an alias of %s, use its primary email instead", groups.Filename, group.Email, email, aliasOwners[email]))
+			case !domains.Has(emailDomain(email)):
+				// external member
+			case users.Protected.IsUserProtected(email), users.Ignore.IsUserIgnored(email, ""):
+			case groups.Protected.IsGroupProtected(email), groups.Ignore.IsGroupIgnored(email, ""):
+			default:
+				allErrors = append(allErrors, fmt.Errorf("%s: [group: %s] member %s is neither a user in %s nor a group", groups.Filename, group.Email, email, users.Filename))
+			}
+		}
+	}
+
+	return allErrors
+}
+
+func userConfigured(cfg *Config, email string) bool {
+	for _, user := range cfg.Users {
+		if user.PrimaryEmail == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReferences(t *testing.T) {
+	orgUnits := &Config{
+		OrgUnits: []OrgUnit{
+			{Name: "Engineering", ParentOrgUnitPath: "/"},
+		},
+		Protected: Protected{OrgUnits: []string{"/Legacy"}},
+	}
+
+	users := &Config{
+		Users: []User{
+			{PrimaryEmail: "jane@example.com", OrgUnitPath: "/Engineering", Aliases: []string{"jd@example.com"}},
+			{PrimaryEmail: "bob@example.com", OrgUnitPath: "/Legacy", Employee: Employee{ManagerEmail: "jane@example.com"}},
+		},
+		Protected: Protected{Users: []string{"admin@example.com"}},
+	}
+
+	groups := &Config{
+		Groups: []Group{
+			{Email: "team@example.com", Members: []Member{
+				{Email: "jane@example.com"},
+				{Email: "admin@example.com"},
+				{Email: "partner@example.org"},
+				{Email: "all@example.com"},
+				{Type: MemberTypeCustomer},
+			}},
+			{Email: "all@example.com"},
+		},
+	}
+
+	validate := func() []error {
+		return ValidateReferences(
+			File{Config: orgUnits, Filename: "orgunits.yaml"},
+			File{Config: users, Filename: "users.yaml"},
+			File{Config: groups, Filename: "groups.yaml"},
+		)
+	}
+
+	if errs := validate(); len(errs) > 0 {
+		t.Fatalf("Expected configs to be valid, but got %v", errs)
+	}
+
+	users.Users = append(users.Users, User{
+		PrimaryEmail: "max@example.com",
+		OrgUnitPath:  "/Sales",
+		Employee:     Employee{ManagerEmail: "boss@example.com"},
+	})
+	users.DataTransfer = &DataTransfer{FallbackOwner: "nobody@example.com"}
+	groups.Groups[1].Members = []Member{
+		{Email: "jd@example.com"},
+		{Email: "unknown@example.com"},
+	}
+
+	expected := []string{
+		"users.yaml: [user: max@example.com] org unit /Sales is not configured in orgunits.yaml",
+		"users.yaml: [user: max@example.com] manager boss@example.com is not a configured user",
+		"users.yaml: [dataTransfer] fallback owner nobody@example.com is not a configured user",
+		"groups.yaml: [group: all@example.com] member jd@example.com is an alias of user jane@example.com, use its primary email instead",
+		"groups.yaml: [group: all@example.com] member unknown@example.com is neither a user in users.yaml nor a group",
+	}
+
+	errs := []string{}
+	for _, err := range validate() {
+		errs = append(errs, err.Error())
+	}
+
+	if strings.Join(errs, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected errors\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(errs, "\n"))
+	}
+}
+
+func TestValidateReferencesWithoutUsers(t *testing.T) {
+	groups := &Config{
+		Groups: []Group{
+			{Email: "team@example.com", Members: []Member{{Email: "unknown@example.com"}}},
+		},
+	}
+
+	errs := ValidateReferences(
+		File{Config: &Config{}, Filename: "orgunits.yaml"},
+		File{},
+		File{Config: groups, Filename: "groups.yaml"},
+	)
+
+	if len(errs) > 0 {
+		t.Fatalf("Expected members not to be checked without a users config, but got %v", errs)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// user removal policies
+	UserRemovalDelete            = "delete"
+	UserRemovalSuspend           = "suspend"
+	UserRemovalMoveToOrgUnit     = "moveToOrgUnit"
+	UserRemovalSuspendThenDelete = "suspendThenDeleteAfter"
+	UserRemovalDefault           = UserRemovalDelete
+)
+
+var allUserRemovalPolicies = sets.NewString(
+	UserRemovalDelete,
+	UserRemovalSuspend,
+	UserRemovalMoveToOrgUnit,
+	UserRemovalSuspendThenDelete,
+)
+
+// UserRemovalPolicy decides what happens to users that exist in GSuite,
+// but not in the configuration. In YAML, it is either one of the plain
+// policies "delete" and "suspend", or a single-key mapping like
+// "moveToOrgUnit: /Offboarded" or "suspendThenDeleteAfter: 30d".
+type UserRemovalPolicy struct {
+	Policy string
+
+	// OrgUnitPath is the org unit to move users to (moveToOrgUnit only).
+	OrgUnitPath string
+
+	// GracePeriod is the time between suspending and deleting users
+	// (suspendThenDeleteAfter only).
+	GracePeriod time.Duration
+}
+
+func (p UserRemovalPolicy) IsZero() bool {
+	return p.Policy == ""
+}
+
+func (p *UserRemovalPolicy) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*p = UserRemovalPolicy{Policy: value.Value}
+		return nil
+
+	case yaml.MappingNode:
+		if len(value.Content) != 2 {
+			return fmt.Errorf("line %d: userRemovalPolicy must have exactly one key", value.Line)
+		}
+
+		key := value.Content[0].Value
+		argument := value.Content[1].Value
+
+		switch key {
+		case UserRemovalMoveToOrgUnit:
+			*p = UserRemovalPolicy{Policy: key, OrgUnitPath: argument}
+
+		case UserRemovalSuspendThenDelete:
+			gracePeriod, err := ParseDays(argument)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid %s value: %v", value.Line, key, err)
+			}
+
+			*p = UserRemovalPolicy{Policy: key, GracePeriod: gracePeriod}
+
+		default:
+			return fmt.Errorf("line %d: unknown userRemovalPolicy %q", value.Line, key)
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("line %d: userRemovalPolicy must be a string or a mapping", value.Line)
+	}
+}
+
+func (p UserRemovalPolicy) MarshalYAML() (interface{}, error) {
+	switch p.Policy {
+	case UserRemovalMoveToOrgUnit:
+		return map[string]string{p.Policy: p.OrgUnitPath}, nil
+	case UserRemovalSuspendThenDelete:
+		return map[string]string{p.Policy: FormatDays(p.GracePeriod)}, nil
+	default:
+		return p.Policy, nil
+	}
+}
+
+func (p UserRemovalPolicy) String() string {
+	switch p.Policy {
+	case UserRemovalMoveToOrgUnit:
+		return fmt.Sprintf("move to %s", p.OrgUnitPath)
+	case UserRemovalSuspendThenDelete:
+		return fmt.Sprintf("suspend, then delete after %s", FormatDays(p.GracePeriod))
+	default:
+		return p.Policy
+	}
+}
+
+// DataTransfer enables transferring the Drive and Calendar data of users
+// to their manager before they are deleted. If a user has no manager, or
+// the manager is not a configured user themselves, the data is transferred
+// to the FallbackOwner instead. Users without any recipient are not deleted.
+type DataTransfer struct {
+	FallbackOwner string `yaml:"fallbackOwner,omitempty" json:"fallbackOwner,omitempty"`
+}
+
+// ParseDays parses a duration that is either given in days (e.g. "30d")
+// or in a format understood by time.ParseDuration (e.g. "36h").
+func ParseDays(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// FormatDays is the inverse of ParseDays.
+func FormatDays(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+
+	return d.String()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestUserRemovalPolicy(t *testing.T) {
+	testcases := []struct {
+		yaml     string
+		expected UserRemovalPolicy
+		invalid  bool
+	}{
+		{
+			yaml:     `suspend`,
+			expected: UserRemovalPolicy{Policy: UserRemovalSuspend},
+		},
+		{
+			yaml:     `moveToOrgUnit: /Offboarded`,
+			expected: UserRemovalPolicy{Policy: UserRemovalMoveToOrgUnit, OrgUnitPath: "/Offboarded"},
+		},
+		{
+			yaml:     `suspendThenDeleteAfter: 30d`,
+			expected: UserRemovalPolicy{Policy: UserRemovalSuspendThenDelete, GracePeriod: 30 * 24 * time.Hour},
+		},
+		{
+			yaml:     `suspendThenDeleteAfter: 36h`,
+			expected: UserRemovalPolicy{Policy: UserRemovalSuspendThenDelete, GracePeriod: 36 * time.Hour},
+		},
+		{
+			yaml:    `suspendThenDeleteAfter: soon`,
+			invalid: true,
+		},
+		{
+			yaml:    `archive: /Offboarded`,
+			invalid: true,
+		},
+		{
+			yaml:    `[suspend]`,
+			invalid: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.yaml, func(t *testing.T) {
+			policy := UserRemovalPolicy{}
+
+			err := yaml.Unmarshal([]byte(tc.yaml), &policy)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("Expected an error, but got %+v", policy)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to parse policy: %v", err)
+			}
+
+			if policy != tc.expected {
+				t.Fatalf("Expected %+v, but got %+v", tc.expected, policy)
+			}
+
+			encoded, err := yaml.Marshal(policy)
+			if err != nil {
+				t.Fatalf("Failed to encode policy: %v", err)
+			}
+
+			roundtripped := UserRemovalPolicy{}
+			if err := yaml.Unmarshal(encoded, &roundtripped); err != nil {
+				t.Fatalf("Failed to parse encoded policy: %v", err)
+			}
+
+			if roundtripped != policy {
+				t.Fatalf("Expected %+v after round trip, but got %+v", policy, roundtripped)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Selection restricts a sync to a subset of the resources. An empty
+// selection includes everything; otherwise only the selected resources
+// are compared and changed, and resource kinds without any selector are
+// not synchronized at all.
+type Selection struct {
+	// Users and Groups are emails or glob patterns (see path.Match),
+	// matched case-insensitively.
+	Users  []string
+	Groups []string
+
+	// OrgUnitTrees are org unit paths; each selects the org unit, all of
+	// its children and all users in them.
+	OrgUnitTrees []string
+}
+
+func (s *Selection) Empty() bool {
+	return s == nil || (len(s.Users) == 0 && len(s.Groups) == 0 && len(s.OrgUnitTrees) == 0)
+}
+
+func (s *Selection) SelectsUsers() bool {
+	return s.Empty() || len(s.Users) > 0 || len(s.OrgUnitTrees) > 0
+}
+
+func (s *Selection) SelectsGroups() bool {
+	return s.Empty() || len(s.Groups) > 0
+}
+
+func (s *Selection) SelectsOrgUnits() bool {
+	return s.Empty() || len(s.OrgUnitTrees) > 0
+}
+
+func (s *Selection) IncludesUser(email string, orgUnitPath string) bool {
+	if s.Empty() {
+		return true
+	}
+
+	return matchesAny(s.Users, email) || s.inOrgUnitTrees(orgUnitPath)
+}
+
+func (s *Selection) IncludesGroup(email string) bool {
+	if s.Empty() {
+		return true
+	}
+
+	return matchesAny(s.Groups, email)
+}
+
+func (s *Selection) IncludesOrgUnit(orgUnitPath string) bool {
+	if s.Empty() {
+		return true
+	}
+
+	return s.inOrgUnitTrees(orgUnitPath)
+}
+
+func (s *Selection) inOrgUnitTrees(orgUnitPath string) bool {
+	for _, tree := range s.OrgUnitTrees {
+		if inOrgUnitTree(tree, orgUnitPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Selection) Validate() []error {
+	var allErrors []error
+
+	for _, pattern := range append(append([]string{}, s.Users...), s.Groups...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("invalid pattern %q: %v", pattern, err))
+		}
+	}
+
+	for _, tree := range s.OrgUnitTrees {
+		if !strings.HasPrefix(tree, "/") {
+			allErrors = append(allErrors, fmt.Errorf("org unit path %q must start with a slash", tree))
+		}
+	}
+
+	return allErrors
+}
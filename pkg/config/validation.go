@@ -19,8 +19,10 @@ package config
 import (
 	"errors"
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -30,6 +32,19 @@ func validateEmailFormat(email string) bool {
 	return len(email) < 129 && strings.Contains(email, "@")
 }
 
+// validatePatterns checks that all protection patterns are well-formed
+func validatePatterns(kind string, patterns []string) []error {
+	var allErrors []error
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("[protected %s: %s] invalid pattern: %v", kind, pattern, err))
+		}
+	}
+
+	return allErrors
+}
+
 func (c *Config) ValidateUsers() []error {
 	var allErrors []error
 	re164 := regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
@@ -39,20 +54,51 @@ func (c *Config) ValidateUsers() []error {
 		allErrors = append(allErrors, errors.New("no organization configured"))
 	}
 
+	allErrors = append(allErrors, validatePatterns("users", c.Protected.Users)...)
+
+	for _, selector := range c.Ignore.Users {
+		if err := selector.validate("users"); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
+	// validate removal policy
+	policy := c.UserRemovalPolicy
+	if !allUserRemovalPolicies.Has(policy.Policy) {
+		allErrors = append(allErrors, fmt.Errorf("invalid userRemovalPolicy %q, must be one of %v", policy.Policy, allUserRemovalPolicies.List()))
+	}
+
+	if policy.Policy == UserRemovalMoveToOrgUnit && !strings.HasPrefix(policy.OrgUnitPath, "/") {
+		allErrors = append(allErrors, fmt.Errorf("the %s org unit path %q must start with a slash", policy.Policy, policy.OrgUnitPath))
+	}
+
+	if policy.Policy == UserRemovalSuspendThenDelete && policy.GracePeriod <= 0 {
+		allErrors = append(allErrors, fmt.Errorf("the %s grace period must be positive", policy.Policy))
+	}
+
+	if c.DataTransfer != nil && c.DataTransfer.FallbackOwner != "" && !validateEmailFormat(c.DataTransfer.FallbackOwner) {
+		allErrors = append(allErrors, fmt.Errorf("data transfer fallback owner is not a valid email-address (%s)", c.DataTransfer.FallbackOwner))
+	}
+
 	// validate users
 	userEmails := sets.NewString()
 	for _, user := range c.Users {
-		if userEmails.Has(user.PrimaryEmail) {
-			allErrors = append(allErrors, fmt.Errorf("duplicate user defined (user: %s)", user.PrimaryEmail))
-		}
 		userEmails.Insert(user.PrimaryEmail)
+	}
 
+	// duplicates are found by ValidateEmails, across users and groups
+	previousEmails := sets.NewString()
+	for _, user := range c.Users {
 		if user.PrimaryEmail == "" {
 			allErrors = append(allErrors, fmt.Errorf("primary email is required (user: %s)", user.LastName))
 		} else if !validateEmailFormat(user.PrimaryEmail) {
 			allErrors = append(allErrors, fmt.Errorf("primary email is not a valid email-address (user: %s)", user.PrimaryEmail))
 		}
 
+		if c.Ignore.IsUserIgnored(user.PrimaryEmail, user.OrgUnitPath) {
+			allErrors = append(allErrors, fmt.Errorf("user is configured, but matches an ignore selector (user: %s)", user.PrimaryEmail))
+		}
+
 		if user.FirstName == "" || user.LastName == "" {
 			allErrors = append(allErrors, fmt.Errorf("given and family names are required (user: %s)", user.PrimaryEmail))
 		}
@@ -77,6 +123,19 @@ func (c *Config) ValidateUsers() []error {
 			}
 		}
 
+		for _, previousEmail := range user.PreviousEmails {
+			switch {
+			case !validateEmailFormat(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("previous email %q is not a valid email-address (user: %s)", previousEmail, user.PrimaryEmail))
+			case userEmails.Has(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("previous email %s is the primary email of a configured user (user: %s)", previousEmail, user.PrimaryEmail))
+			case previousEmails.Has(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("previous email %s is used by multiple users (user: %s)", previousEmail, user.PrimaryEmail))
+			}
+
+			previousEmails.Insert(previousEmail)
+		}
+
 		if len(user.Licenses) > 0 {
 			for _, license := range user.Licenses {
 				found := false
@@ -103,46 +162,62 @@ func (c *Config) ValidateGroups() []error {
 		allErrors = append(allErrors, errors.New("no organization configured"))
 	}
 
+	allErrors = append(allErrors, validatePatterns("groups", c.Protected.Groups)...)
+
+	for _, selector := range c.Ignore.Groups {
+		if err := selector.validate("groups"); err != nil {
+			allErrors = append(allErrors, err)
+		}
+	}
+
+	if !allGroupSettingsPolicies.Has(c.GroupSettingsPolicy) {
+		allErrors = append(allErrors, fmt.Errorf("invalid groupSettingsPolicy %q, must be one of %v", c.GroupSettingsPolicy, allGroupSettingsPolicies.List()))
+	}
+
+	if c.GroupDefaults != nil {
+		allErrors = append(allErrors, validateGroupSettings("groupDefaults", *c.GroupDefaults)...)
+	}
+
 	// validate groups
 	groupEmails := sets.NewString()
 	for _, group := range c.Groups {
-		if groupEmails.Has(group.Email) {
-			allErrors = append(allErrors, fmt.Errorf("[group: %s] duplicate group email defined", group.Email))
-		}
 		groupEmails.Insert(group.Email)
+	}
 
+	// duplicates are found by ValidateEmails, across users and groups
+	previousEmails := sets.NewString()
+	for _, group := range c.Groups {
 		if !validateEmailFormat(group.Email) {
 			allErrors = append(allErrors, fmt.Errorf("[group: %s] group email is not a valid email address", group.Email))
 		}
 
-		if group.WhoCanContactOwner != "" {
-			if !allWhoCanContactOwnerOptions.Has(strings.ToUpper(group.WhoCanContactOwner)) {
-				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid value specified for 'whoCanContactOwner' field, must be one of %v", group.Name, allWhoCanContactOwnerOptions.List()))
+		for _, alias := range group.Aliases {
+			if !validateEmailFormat(alias) {
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] alias %q is not a valid email address", group.Email, alias))
 			}
 		}
 
-		if group.WhoCanViewMembership != "" {
-			if !allWhoCanViewMembershipOptions.Has(strings.ToUpper(group.WhoCanViewMembership)) {
-				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid value specified for 'whoCanViewMembers' field, must be one of %v", group.Name, allWhoCanViewMembershipOptions.List()))
-			}
+		if c.Ignore.IsGroupIgnored(group.Email, group.Name) {
+			allErrors = append(allErrors, fmt.Errorf("[group: %s] group is configured, but matches an ignore selector", group.Email))
 		}
 
-		if group.WhoCanApproveMembers != "" {
-			if !allWhoCanApproveMembersOptions.Has(strings.ToUpper(group.WhoCanApproveMembers)) {
-				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid value specified for 'whoCanApproveMembers' field, must be one of %v", group.Name, allWhoCanApproveMembersOptions.List()))
+		for _, previousEmail := range group.PreviousEmails {
+			switch {
+			case !validateEmailFormat(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] previous email %q is not a valid email address", group.Email, previousEmail))
+			case groupEmails.Has(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] previous email %s is the email of a configured group", group.Email, previousEmail))
+			case previousEmails.Has(previousEmail):
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] previous email %s is used by multiple groups", group.Email, previousEmail))
 			}
-		}
 
-		if group.WhoCanPostMessage != "" {
-			if !allWhoCanPostMessageOptions.Has(strings.ToUpper(group.WhoCanPostMessage)) {
-				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid value specified for 'whoCanPostMessage' field, must be one of %v", group.Name, allWhoCanPostMessageOptions.List()))
-			}
+			previousEmails.Insert(previousEmail)
 		}
 
-		if group.WhoCanJoin != "" {
-			if !allWhoCanJoinOptions.Has(strings.ToUpper(group.WhoCanJoin)) {
-				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid value specified for 'whoCanJoin' field, must be one of %v", group.Name, allWhoCanJoinOptions.List()))
-			}
+		allErrors = append(allErrors, validateGroupSettings(fmt.Sprintf("group: %s", group.Name), group.GroupSettings)...)
+
+		if !allMembershipModes.Has(group.MembershipMode) {
+			allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid membershipMode %q, must be one of %v", group.Name, group.MembershipMode, allMembershipModes.List()))
 		}
 
 		memberEmails := sets.NewString()
@@ -155,12 +230,133 @@ func (c *Config) ValidateGroups() []error {
 			if !allMemberRoles.Has(member.Role) {
 				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid member role specified for %q, must be one of %v", group.Name, member.Email, allMemberRoles.List()))
 			}
+
+			if member.Type != "" && !allMemberTypes.Has(member.Type) {
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid member type specified for %q, must be one of %v", group.Name, member.Email, allMemberTypes.List()))
+			}
+
+			if group.MembershipMode == MembershipModeOwnersOnly && member.Role != MemberRoleOwner && member.Role != MemberRoleManager {
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] member %q must be %s or %s, as only those are managed in %s groups", group.Name, member.Email, MemberRoleOwner, MemberRoleManager, MembershipModeOwnersOnly))
+			}
+
+			allErrors = append(allErrors, validateMemberDates(group, member)...)
+
+			if member.Delivery != "" && !allMemberDeliverySettings.Has(member.Delivery) {
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid delivery specified for %q, must be one of %v", group.Name, member.Email, allMemberDeliverySettings.List()))
+			}
+
+			switch {
+			case member.Type == MemberTypeCustomer && member.Email != "":
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] member %q of type %s must not have an email", group.Name, member.Email, member.Type))
+			case member.Type != MemberTypeCustomer && member.Email == "":
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] member without email must be of type %s", group.Name, MemberTypeCustomer))
+			case member.Type != "" && member.Type != MemberTypeGroup && groupEmails.Has(member.Email):
+				allErrors = append(allErrors, fmt.Errorf("[group: %s] member %q is a configured group, but has type %s", group.Name, member.Email, member.Type))
+			}
 		}
 	}
 
+	for _, cycle := range membershipCycles(c.Groups) {
+		allErrors = append(allErrors, fmt.Errorf("[group: %s] membership cycle: %s", cycle[0], strings.Join(cycle, " → ")))
+	}
+
+	return allErrors
+}
+
+func validateMemberDates(group Group, member Member) []error {
+	var allErrors []error
+
+	parse := func(name string, value string) (time.Time, bool) {
+		if value == "" {
+			return time.Time{}, false
+		}
+
+		date, err := time.Parse(MemberDateLayout, value)
+		if err != nil {
+			allErrors = append(allErrors, fmt.Errorf("[group: %s] invalid '%s' date %q for %q, must be YYYY-MM-DD", group.Name, name, value, member.Email))
+			return time.Time{}, false
+		}
+
+		return date, true
+	}
+
+	since, hasSince := parse("since", member.Since)
+	expires, hasExpires := parse("expires", member.Expires)
+	if hasSince && hasExpires && expires.Before(since) {
+		allErrors = append(allErrors, fmt.Errorf("[group: %s] membership of %q expires before it starts", group.Name, member.Email))
+	}
+
+	return allErrors
+}
+
+// validateGroupSettings checks the settings of a group or the group
+// defaults; context is used to prefix the errors.
+func validateGroupSettings(context string, settings GroupSettings) []error {
+	var allErrors []error
+
+	enumSettings := []struct {
+		name    string
+		value   string
+		options sets.String
+	}{
+		{"whoCanContactOwner", settings.WhoCanContactOwner, allWhoCanContactOwnerOptions},
+		{"whoCanViewMembers", settings.WhoCanViewMembership, allWhoCanViewMembershipOptions},
+		{"whoCanApproveMembers", settings.WhoCanApproveMembers, allWhoCanApproveMembersOptions},
+		{"whoCanPostMessage", settings.WhoCanPostMessage, allWhoCanPostMessageOptions},
+		{"whoCanJoin", settings.WhoCanJoin, allWhoCanJoinOptions},
+		{"whoCanModerateMembers", settings.WhoCanModerateMembers, allWhoCanModerateOptions},
+		{"whoCanModerateContent", settings.WhoCanModerateContent, allWhoCanModerateOptions},
+		{"whoCanAssistContent", settings.WhoCanAssistContent, allWhoCanAssistContentOptions},
+		{"whoCanDiscoverGroup", settings.WhoCanDiscoverGroup, allWhoCanDiscoverGroupOptions},
+		{"whoCanLeaveGroup", settings.WhoCanLeaveGroup, allWhoCanLeaveGroupOptions},
+		{"whoCanViewGroup", settings.WhoCanViewGroup, allWhoCanViewGroupOptions},
+		{"messageModerationLevel", settings.MessageModerationLevel, allMessageModerationLevels},
+		{"spamModerationLevel", settings.SpamModerationLevel, allSpamModerationLevels},
+		{"replyTo", settings.ReplyTo, allReplyToOptions},
+		{"defaultSender", settings.DefaultSender, allDefaultSenderOptions},
+	}
+
+	for _, setting := range enumSettings {
+		if setting.value != "" && !setting.options.Has(strings.ToUpper(setting.value)) {
+			allErrors = append(allErrors, fmt.Errorf("[%s] invalid value specified for '%s' field, must be one of %v", context, setting.name, setting.options.List()))
+		}
+	}
+
+	customReplyTo := stringValue(settings.CustomReplyTo)
+
+	if settings.ReplyTo == GroupOptionReplyToCustom && customReplyTo == "" {
+		allErrors = append(allErrors, fmt.Errorf("[%s] 'customReplyTo' is required if 'replyTo' is %s", context, GroupOptionReplyToCustom))
+	}
+
+	if customReplyTo != "" && !validateEmailFormat(customReplyTo) {
+		allErrors = append(allErrors, fmt.Errorf("[%s] 'customReplyTo' is not a valid email address", context))
+	}
+
+	if len(stringValue(settings.CustomFooterText)) > 1000 {
+		allErrors = append(allErrors, fmt.Errorf("[%s] 'customFooterText' must not be longer than 1000 characters", context))
+	}
+
+	if len(stringValue(settings.DefaultMessageDenyNotificationText)) > 10000 {
+		allErrors = append(allErrors, fmt.Errorf("[%s] 'defaultMessageDenyNotificationText' must not be longer than 10000 characters", context))
+	}
+
+	// GSuite forces archive-only groups to NONE_CAN_POST and rejects it for all others;
+	// this can only be checked if both settings are managed
+	if settings.WhoCanPostMessage != "" && settings.ArchiveOnly != nil && *settings.ArchiveOnly != (strings.ToUpper(settings.WhoCanPostMessage) == GroupOptionNoneCanPostMessage) {
+		allErrors = append(allErrors, fmt.Errorf("[%s] 'whoCanPostMessage' must be %s if and only if 'archiveOnly' is true", context, GroupOptionNoneCanPostMessage))
+	}
+
 	return allErrors
 }
 
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
 func (c *Config) ValidateOrgUnits() []error {
 	var allErrors []error
 
@@ -169,24 +365,64 @@ func (c *Config) ValidateOrgUnits() []error {
 		allErrors = append(allErrors, errors.New("no organization configured"))
 	}
 
+	allErrors = append(allErrors, validatePatterns("orgUnits", c.Protected.OrgUnits)...)
+
+	for _, tree := range c.Ignore.OrgUnits {
+		if !strings.HasPrefix(tree, "/") {
+			allErrors = append(allErrors, fmt.Errorf("[ignore orgUnits: %s] org unit path must start with a slash", tree))
+		}
+	}
+
 	// validate org units
-	unitNames := sets.NewString()
+	unitPaths := sets.NewString()
 	for _, orgUnit := range c.OrgUnits {
-		if unitNames.Has(orgUnit.Name) {
-			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] duplicate org unit defined", orgUnit.Name))
+		unitPaths.Insert(orgUnit.OrgUnitPath())
+	}
+
+	seenPaths := sets.NewString()
+	seenPreviousPaths := sets.NewString()
+	for _, orgUnit := range c.OrgUnits {
+		orgUnitPath := orgUnit.OrgUnitPath()
+
+		if seenPaths.Has(orgUnitPath) {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] duplicate org unit defined", orgUnitPath))
 		}
-		unitNames.Insert(orgUnit.Name)
+		seenPaths.Insert(orgUnitPath)
 
 		if orgUnit.Name == "" {
-			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] no name specified", orgUnit.Name))
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] no name specified", orgUnitPath))
+		} else if strings.Contains(orgUnit.Name, "/") {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] name must not contain slashes", orgUnitPath))
 		}
 
-		if orgUnit.ParentOrgUnitPath == "" {
-			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] no parentOrgUnitPath specified", orgUnit.Name))
-		} else if !strings.HasPrefix(orgUnit.ParentOrgUnitPath, "/") {
-			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] parentOrgUnitPath must start with a slash", orgUnit.Name))
+		parent := orgUnit.ParentOrgUnitPath
+
+		if parent == "" {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] no parentOrgUnitPath specified", orgUnitPath))
+		} else if !strings.HasPrefix(parent, "/") {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] parentOrgUnitPath must start with a slash", orgUnitPath))
+		} else if parent != "/" && strings.HasSuffix(parent, "/") {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] parentOrgUnitPath must not end with a slash", orgUnitPath))
+		}
+
+		if c.Ignore.IsOrgUnitIgnored(orgUnitPath) {
+			allErrors = append(allErrors, fmt.Errorf("[org unit: %s] org unit is configured, but matches an ignore selector", orgUnitPath))
 		}
 
+		for _, previousPath := range orgUnit.PreviousPaths {
+			switch {
+			case !strings.HasPrefix(previousPath, "/"):
+				allErrors = append(allErrors, fmt.Errorf("[org unit: %s] previous path %s must start with a slash", orgUnitPath, previousPath))
+			case previousPath == "/":
+				allErrors = append(allErrors, fmt.Errorf("[org unit: %s] previous path must not be the root org unit", orgUnitPath))
+			case unitPaths.Has(previousPath):
+				allErrors = append(allErrors, fmt.Errorf("[org unit: %s] previous path %s is a configured org unit", orgUnitPath, previousPath))
+			case seenPreviousPaths.Has(previousPath):
+				allErrors = append(allErrors, fmt.Errorf("[org unit: %s] previous path %s is used by multiple org units", orgUnitPath, previousPath))
+			}
+
+			seenPreviousPaths.Insert(previousPath)
+		}
 	}
 
 	return allErrors
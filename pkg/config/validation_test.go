@@ -0,0 +1,311 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateOrgUnits(t *testing.T) {
+	testcases := []struct {
+		name     string
+		orgUnits []OrgUnit
+		valid    bool
+	}{
+		{
+			name: "same name under different parents",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/"},
+				{Name: "Sales", ParentOrgUnitPath: "/"},
+				{Name: "Interns", ParentOrgUnitPath: "/Engineering"},
+				{Name: "Interns", ParentOrgUnitPath: "/Sales"},
+			},
+			valid: true,
+		},
+		{
+			name: "unconfigured parent",
+			orgUnits: []OrgUnit{
+				{Name: "Platform", ParentOrgUnitPath: "/Engineering"},
+			},
+			valid: true,
+		},
+		{
+			name: "duplicate path",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/"},
+				{Name: "Engineering", ParentOrgUnitPath: "/"},
+			},
+		},
+		{
+			name: "slash in name",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering/Platform", ParentOrgUnitPath: "/"},
+			},
+		},
+		{
+			name: "trailing slash in parent",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/"},
+				{Name: "Platform", ParentOrgUnitPath: "/Engineering/"},
+			},
+		},
+		{
+			name: "previous paths",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/", PreviousPaths: []string{"/Eng", "/Development"}},
+			},
+			valid: true,
+		},
+		{
+			name: "relative previous path",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/", PreviousPaths: []string{"Eng"}},
+			},
+		},
+		{
+			name: "root as previous path",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/", PreviousPaths: []string{"/"}},
+			},
+		},
+		{
+			name: "configured org unit as previous path",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/", PreviousPaths: []string{"/Sales"}},
+				{Name: "Sales", ParentOrgUnitPath: "/"},
+			},
+		},
+		{
+			name: "previous path used twice",
+			orgUnits: []OrgUnit{
+				{Name: "Engineering", ParentOrgUnitPath: "/", PreviousPaths: []string{"/Eng"}},
+				{Name: "Sales", ParentOrgUnitPath: "/", PreviousPaths: []string{"/Eng"}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{Organization: "example", OrgUnits: tc.orgUnits}
+
+			errs := cfg.ValidateOrgUnits()
+			if tc.valid && len(errs) > 0 {
+				t.Fatalf("Expected config to be valid, but got %v", errs)
+			}
+			if !tc.valid && len(errs) == 0 {
+				t.Fatal("Expected config to be invalid")
+			}
+		})
+	}
+}
+
+func TestValidateUsers(t *testing.T) {
+	jane := func(previousEmails ...string) User {
+		return User{FirstName: "Jane", LastName: "Doe", PrimaryEmail: "jane.doe@example.com", PreviousEmails: previousEmails}
+	}
+
+	bob := func(previousEmails ...string) User {
+		return User{FirstName: "Bob", LastName: "Smith", PrimaryEmail: "robert@example.com", PreviousEmails: previousEmails}
+	}
+
+	testcases := []struct {
+		name  string
+		users []User
+		valid bool
+	}{
+		{
+			name:  "previous emails",
+			users: []User{jane("jane@example.com"), bob("bob@example.com")},
+			valid: true,
+		},
+		{
+			name:  "invalid previous email",
+			users: []User{jane("jane")},
+		},
+		{
+			name:  "configured user as previous email",
+			users: []User{jane("robert@example.com"), bob()},
+		},
+		{
+			name:  "previous email used twice",
+			users: []User{jane("j@example.com"), bob("j@example.com")},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{
+				Organization:      "example",
+				UserRemovalPolicy: UserRemovalPolicy{Policy: UserRemovalDelete},
+				Users:             tc.users,
+			}
+
+			errs := cfg.ValidateUsers()
+			if tc.valid && len(errs) > 0 {
+				t.Fatalf("Expected config to be valid, but got %v", errs)
+			}
+			if !tc.valid && len(errs) == 0 {
+				t.Fatal("Expected config to be invalid")
+			}
+		})
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	crew := func(previousEmails ...string) Group {
+		return Group{Name: "Crew", Email: "crew@example.com", PreviousEmails: previousEmails}
+	}
+
+	staff := func(previousEmails ...string) Group {
+		return Group{Name: "Staff", Email: "staff@example.com", PreviousEmails: previousEmails}
+	}
+
+	testcases := []struct {
+		name   string
+		groups []Group
+		valid  bool
+	}{
+		{
+			name:   "previous emails",
+			groups: []Group{crew("team@example.com"), staff("all@example.com")},
+			valid:  true,
+		},
+		{
+			name:   "invalid previous email",
+			groups: []Group{crew("team")},
+		},
+		{
+			name:   "configured group as previous email",
+			groups: []Group{crew("staff@example.com"), staff()},
+		},
+		{
+			name:   "previous email used twice",
+			groups: []Group{crew("team@example.com"), staff("team@example.com")},
+		},
+		{
+			name: "member types",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{
+					{Email: "staff@example.com", Role: MemberRoleMember, Type: MemberTypeGroup},
+					{Email: "partner@example.org", Role: MemberRoleMember, Type: MemberTypeExternal, Delivery: MemberDeliveryDigest},
+					{Role: MemberRoleMember, Type: MemberTypeCustomer},
+				}},
+				staff(),
+			},
+			valid: true,
+		},
+		{
+			name: "invalid extended setting",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", GroupSettings: GroupSettings{WhoCanModerateContent: "EVERYONE"}},
+			},
+		},
+		{
+			name: "unknown member type",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Type: "ROBOT"}}},
+			},
+		},
+		{
+			name: "invalid delivery setting",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Delivery: "WEEKLY"}}},
+			},
+		},
+		{
+			name: "owners only",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", MembershipMode: MembershipModeOwnersOnly, Members: []Member{
+					{Email: "jane@example.com", Role: MemberRoleOwner},
+					{Email: "bob@example.com", Role: MemberRoleManager},
+				}},
+			},
+			valid: true,
+		},
+		{
+			name: "regular member in owners only group",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", MembershipMode: MembershipModeOwnersOnly, Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember}}},
+			},
+		},
+		{
+			name: "unknown membership mode",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", MembershipMode: "exclusive"},
+			},
+		},
+		{
+			name: "membership period",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Since: "2021-12-01", Expires: "2021-12-31"}}},
+			},
+			valid: true,
+		},
+		{
+			name: "invalid membership date",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Expires: "31.12.2021"}}},
+			},
+		},
+		{
+			name: "membership expires before it starts",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Since: "2021-12-31", Expires: "2021-12-01"}}},
+			},
+		},
+		{
+			name: "customer member with email",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "jane@example.com", Role: MemberRoleMember, Type: MemberTypeCustomer}}},
+			},
+		},
+		{
+			name: "configured group as user member",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "staff@example.com", Role: MemberRoleMember, Type: MemberTypeUser}}},
+				staff(),
+			},
+		},
+		{
+			name: "membership cycle",
+			groups: []Group{
+				{Name: "Crew", Email: "crew@example.com", Members: []Member{{Email: "staff@example.com", Role: MemberRoleMember}}},
+				{Name: "Staff", Email: "staff@example.com", Members: []Member{{Email: "crew@example.com", Role: MemberRoleMember}}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := range tc.groups {
+				if tc.groups[i].MembershipMode == "" {
+					tc.groups[i].MembershipMode = MembershipModeDefault
+				}
+			}
+
+			cfg := &Config{Organization: "example", GroupSettingsPolicy: GroupSettingsManaged, Groups: tc.groups}
+
+			errs := cfg.ValidateGroups()
+			if tc.valid && len(errs) > 0 {
+				t.Fatalf("Expected config to be valid, but got %v", errs)
+			}
+			if !tc.valid && len(errs) == 0 {
+				t.Fatal("Expected config to be invalid")
+			}
+		})
+	}
+}
@@ -20,12 +20,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
 	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func ExportOrgUnits(ctx context.Context, directorySrv *glib.DirectoryService) ([]config.OrgUnit, error) {
+func ExportOrgUnits(ctx context.Context, directorySrv glib.DirectoryClient, ignore *config.Ignore) ([]config.OrgUnit, error) {
 	orgUnits, err := directorySrv.ListOrgUnits(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list org units: %v", err)
@@ -33,6 +36,10 @@ func ExportOrgUnits(ctx context.Context, directorySrv *glib.DirectoryService) ([
 
 	result := []config.OrgUnit{}
 	for _, ou := range orgUnits {
+		if ignore.IsOrgUnitIgnored(ou.OrgUnitPath) {
+			continue
+		}
+
 		log.Printf("  %s", ou.Name)
 		result = append(result, config.ToConfigOrgUnit(ou))
 	}
@@ -40,7 +47,7 @@ func ExportOrgUnits(ctx context.Context, directorySrv *glib.DirectoryService) ([
 	return result, nil
 }
 
-func ExportUsers(ctx context.Context, directorySrv *glib.DirectoryService, licensingSrv *glib.LicensingService, licenseStatus *glib.LicenseStatus) ([]config.User, error) {
+func ExportUsers(ctx context.Context, directorySrv glib.DirectoryClient, licensingSrv glib.LicensingClient, licenseStatus *glib.LicenseStatus, ignore *config.Ignore) ([]config.User, error) {
 	users, err := directorySrv.ListUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %v", err)
@@ -48,6 +55,10 @@ func ExportUsers(ctx context.Context, directorySrv *glib.DirectoryService, licen
 
 	result := []config.User{}
 	for _, user := range users {
+		if ignore.IsUserIgnored(user.PrimaryEmail, user.OrgUnitPath) {
+			continue
+		}
+
 		log.Printf("  %s", user.PrimaryEmail)
 
 		userLicenses := licenseStatus.GetLicensesForUser(user)
@@ -63,29 +74,43 @@ func ExportUsers(ctx context.Context, directorySrv *glib.DirectoryService, licen
 	return result, nil
 }
 
-func ExportGroups(ctx context.Context, directorySrv *glib.DirectoryService, groupsSettingsSrv *glib.GroupsSettingsService) ([]config.Group, error) {
-	groups, err := directorySrv.ListGroups(ctx)
+// ExportGroups exports all groups that are not ignored by the given
+// configuration. Configured groups keep their membership mode and only
+// the members that are managed in this mode are exported. Configured
+// membership periods are kept, as are memberships that have not started
+// yet.
+func ExportGroups(ctx context.Context, directorySrv glib.DirectoryClient, groupsSettingsSrv glib.GroupsSettingsClient, cfg *config.Config, concurrency int) ([]config.Group, error) {
+	liveGroups, err := directorySrv.ListGroups(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list groups: %v", err)
 	}
 
+	groups := []*directoryv1.Group{}
+	for _, group := range liveGroups {
+		if !cfg.Ignore.IsGroupIgnored(group.Email, group.Name) {
+			groups = append(groups, group)
+		}
+	}
+
+	groupDetails, err := glib.FetchGroupDetails(ctx, directorySrv, groupsSettingsSrv, groups, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	result := []config.Group{}
 	for _, group := range groups {
 		log.Printf("  %s", group.Name)
 
-		settings, err := groupsSettingsSrv.GetSettings(ctx, group.Email)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get group settings: %v", err)
-		}
+		details := groupDetails[group.Email]
 
-		members, err := directorySrv.ListMembers(ctx, group)
+		configGroup, err := config.ToConfigGroup(group, details.Settings, details.Members, details.Aliases)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list members: %v", err)
+			return nil, fmt.Errorf("failed to create config group: %v", err)
 		}
 
-		configGroup, err := config.ToConfigGroup(group, settings, members)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create config group: %v", err)
+		if configured := configuredGroup(cfg, configGroup.Email); configured != nil {
+			configGroup.MembershipMode = configured.MembershipMode
+			configGroup.Members = exportMembers(configured, configGroup.Members)
 		}
 
 		result = append(result, configGroup)
@@ -93,3 +118,46 @@ func ExportGroups(ctx context.Context, directorySrv *glib.DirectoryService, grou
 
 	return result, nil
 }
+
+// exportMembers returns the live members that the configured group
+// manages, with their configured membership periods.
+func exportMembers(configured *config.Group, liveMembers []config.Member) []config.Member {
+	now := time.Now()
+	members := []config.Member{}
+	liveEmails := map[string]bool{}
+
+	for _, member := range liveMembers {
+		liveEmails[member.Email] = true
+
+		if !configured.ManagesMember(member) {
+			continue
+		}
+
+		for _, m := range configured.Members {
+			if m.Email == member.Email {
+				member.Since = m.Since
+				member.Expires = m.Expires
+			}
+		}
+
+		members = append(members, member)
+	}
+
+	for _, member := range configured.Members {
+		if !liveEmails[member.Email] && member.Pending(now) {
+			members = append(members, member)
+		}
+	}
+
+	return members
+}
+
+func configuredGroup(cfg *config.Config, email string) *config.Group {
+	for i, group := range cfg.Groups {
+		if group.Email == email {
+			return &cfg.Groups[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"context"
+
+	datatransferv1 "google.golang.org/api/admin/datatransfer/v1"
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// DirectoryClient is the part of the Directory API that GMan relies on.
+// It is implemented by DirectoryService and by the in-memory fake in
+// the glib/fake package.
+type DirectoryClient interface {
+	CustomerID(ctx context.Context) (string, error)
+
+	ListUsers(ctx context.Context) ([]*directoryv1.User, error)
+	GetUser(ctx context.Context, email string) (*directoryv1.User, error)
+	CreateUser(ctx context.Context, user *directoryv1.User) (*directoryv1.User, error)
+	DeleteUser(ctx context.Context, user *directoryv1.User) error
+	UpdateUser(ctx context.Context, oldUser *directoryv1.User, newUser *directoryv1.User) (*directoryv1.User, error)
+	GetUserAliases(ctx context.Context, user *directoryv1.User) ([]string, error)
+	CreateUserAlias(ctx context.Context, user *directoryv1.User, alias string) error
+	DeleteUserAlias(ctx context.Context, user *directoryv1.User, alias string) error
+
+	ListGroups(ctx context.Context) ([]*directoryv1.Group, error)
+	CreateGroup(ctx context.Context, group *directoryv1.Group) (*directoryv1.Group, error)
+	DeleteGroup(ctx context.Context, group *directoryv1.Group) error
+	UpdateGroup(ctx context.Context, oldGroup *directoryv1.Group, newGroup *directoryv1.Group) (*directoryv1.Group, error)
+	GetGroupAliases(ctx context.Context, group *directoryv1.Group) ([]string, error)
+	CreateGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error
+	DeleteGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error
+	ListMembers(ctx context.Context, group *directoryv1.Group) ([]*directoryv1.Member, error)
+	AddNewMember(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error
+	RemoveMember(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error
+	UpdateMembership(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error
+
+	ListOrgUnits(ctx context.Context) ([]*directoryv1.OrgUnit, error)
+	CreateOrgUnit(ctx context.Context, orgUnit *directoryv1.OrgUnit) error
+	DeleteOrgUnit(ctx context.Context, orgUnit *directoryv1.OrgUnit) error
+	UpdateOrgUnit(ctx context.Context, oldUnit *directoryv1.OrgUnit, newUnit *directoryv1.OrgUnit) error
+
+	GetSchema(ctx context.Context, name string) (*directoryv1.Schema, error)
+	CreateSchema(ctx context.Context, schema *directoryv1.Schema) (*directoryv1.Schema, error)
+	UpdateSchema(ctx context.Context, oldSchema *directoryv1.Schema, newSchema *directoryv1.Schema) (*directoryv1.Schema, error)
+}
+
+// LicensingClient is the part of the Enterprise License Manager API that
+// GMan relies on.
+type LicensingClient interface {
+	GetLicenses() ([]config.License, error)
+	GetLicenseByName(name string) *config.License
+	LicenseUsages(ctx context.Context, license config.License) ([]string, error)
+	AssignLicense(ctx context.Context, user *directoryv1.User, license config.License) error
+	UnassignLicense(ctx context.Context, user *directoryv1.User, license config.License) error
+}
+
+// GroupsSettingsClient is the part of the Groups Settings API that GMan
+// relies on.
+type GroupsSettingsClient interface {
+	GetSettings(ctx context.Context, groupId string) (*groupssettingsv1.Groups, error)
+	UpdateSettings(ctx context.Context, group *directoryv1.Group, settings *groupssettingsv1.Groups) (*groupssettingsv1.Groups, error)
+}
+
+// DataTransferClient is the part of the Data Transfer API that GMan
+// relies on.
+type DataTransferClient interface {
+	TransferUserData(ctx context.Context, oldOwnerID string, newOwnerID string) (*datatransferv1.DataTransfer, error)
+	GetTransfer(ctx context.Context, transferID string) (*datatransferv1.DataTransfer, error)
+}
+
+var (
+	_ DirectoryClient      = &DirectoryService{}
+	_ LicensingClient      = &LicensingService{}
+	_ GroupsSettingsClient = &GroupsSettingsService{}
+	_ DataTransferClient   = &DataTransferService{}
+)
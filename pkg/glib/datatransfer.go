@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	datatransferv1 "google.golang.org/api/admin/datatransfer/v1"
+)
+
+const (
+	DriveApplication    = "Drive and Docs"
+	CalendarApplication = "Calendar"
+
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+)
+
+// transferParams are the parameters used when transferring data of the
+// given applications.
+var transferParams = map[string][]*datatransferv1.ApplicationTransferParam{
+	DriveApplication: {
+		{Key: "PRIVACY_LEVEL", Value: []string{"PRIVATE", "SHARED"}},
+	},
+	CalendarApplication: {
+		{Key: "RELEASE_RESOURCES", Value: []string{"TRUE"}},
+	},
+}
+
+type DataTransferService struct {
+	*datatransferv1.Service
+
+	// application IDs, keyed by application name
+	applications map[string]int64
+	lock         sync.Mutex
+}
+
+// NewDataTransferService() creates a client for communicating with Google Data Transfer API.
+// If endpoint is not empty, it replaces https://admin.googleapis.com/.
+func NewDataTransferService(ctx context.Context, clientSecretFile string, impersonatedUserEmail string, endpoint string, delay time.Duration, budget *RetryBudget) (*DataTransferService, error) {
+	opts, err := clientOptions(ctx, clientSecretFile, impersonatedUserEmail, endpoint, "", delay, budget, datatransferv1.AdminDatatransferScope)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := datatransferv1.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a new data transfer service: %v", err)
+	}
+
+	transferService := &DataTransferService{
+		Service: srv,
+	}
+
+	return transferService, nil
+}
+
+// applicationIDs returns the IDs of all applications that support data
+// transfers; they are only fetched once.
+func (ts *DataTransferService) applicationIDs(ctx context.Context) (map[string]int64, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	if ts.applications != nil {
+		return ts.applications, nil
+	}
+
+	applications := map[string]int64{}
+	token := ""
+
+	for {
+		response, err := ts.Applications.List().CustomerId("my_customer").PageToken(token).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, application := range response.Applications {
+			applications[application.Name] = application.Id
+		}
+
+		token = response.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+
+	ts.applications = applications
+
+	return applications, nil
+}
+
+// TransferUserData starts transferring the Drive and Calendar data of one
+// user to another. Transfers run asynchronously, use GetTransfer to check
+// on their progress.
+func (ts *DataTransferService) TransferUserData(ctx context.Context, oldOwnerID string, newOwnerID string) (*datatransferv1.DataTransfer, error) {
+	applications, err := ts.applicationIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list applications: %v", err)
+	}
+
+	transfer := &datatransferv1.DataTransfer{
+		OldOwnerUserId: oldOwnerID,
+		NewOwnerUserId: newOwnerID,
+	}
+
+	for _, name := range []string{DriveApplication, CalendarApplication} {
+		id, ok := applications[name]
+		if !ok {
+			return nil, fmt.Errorf("application %q does not support data transfers", name)
+		}
+
+		transfer.ApplicationDataTransfers = append(transfer.ApplicationDataTransfers, &datatransferv1.ApplicationDataTransfer{
+			ApplicationId:             id,
+			ApplicationTransferParams: transferParams[name],
+		})
+	}
+
+	return ts.Transfers.Insert(transfer).Context(ctx).Do()
+}
+
+func (ts *DataTransferService) GetTransfer(ctx context.Context, transferID string) (*datatransferv1.DataTransfer, error) {
+	return ts.Transfers.Get(transferID).Context(ctx).Do()
+}
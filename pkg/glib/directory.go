@@ -19,37 +19,31 @@ package glib
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"sync"
 	"time"
 
-	"golang.org/x/oauth2/google"
 	directoryv1 "google.golang.org/api/admin/directory/v1"
-	"google.golang.org/api/option"
 )
 
 type DirectoryService struct {
 	*directoryv1.Service
 
 	organization string
-	delay        time.Duration
+
+	// the customer ID is only fetched once
+	customerLock sync.Mutex
+	customer     string
 }
 
 // NewDirectoryService() creates a client for communicating with Google Directory API.
-func NewDirectoryService(ctx context.Context, organization string, clientSecretFile string, impersonatedUserEmail string, delay time.Duration, scopes ...string) (*DirectoryService, error) {
-	jsonCredentials, err := ioutil.ReadFile(clientSecretFile)
+// If endpoint is not empty, it replaces https://admin.googleapis.com/.
+func NewDirectoryService(ctx context.Context, organization string, clientSecretFile string, impersonatedUserEmail string, endpoint string, delay time.Duration, budget *RetryBudget, scopes ...string) (*DirectoryService, error) {
+	opts, err := clientOptions(ctx, clientSecretFile, impersonatedUserEmail, endpoint, "", delay, budget, scopes...)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read JSON credentials: %v", err)
+		return nil, err
 	}
 
-	config, err := google.JWTConfigFromJSON(jsonCredentials, scopes...)
-	if err != nil {
-		return nil, fmt.Errorf("unable to process credentials: %v", err)
-	}
-	config.Subject = impersonatedUserEmail
-
-	ts := config.TokenSource(ctx)
-
-	srv, err := directoryv1.NewService(ctx, option.WithTokenSource(ts))
+	srv, err := directoryv1.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a new directory service: %v", err)
 	}
@@ -57,8 +51,26 @@ func NewDirectoryService(ctx context.Context, organization string, clientSecretF
 	dirService := &DirectoryService{
 		Service:      srv,
 		organization: organization,
-		delay:        delay,
 	}
 
 	return dirService, nil
 }
+
+// CustomerID returns the ID of the customer GMan is working with.
+func (ds *DirectoryService) CustomerID(ctx context.Context) (string, error) {
+	ds.customerLock.Lock()
+	defer ds.customerLock.Unlock()
+
+	if ds.customer != "" {
+		return ds.customer, nil
+	}
+
+	customer, err := ds.Customers.Get("my_customer").Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+
+	ds.customer = customer.Id
+
+	return ds.customer, nil
+}
@@ -18,10 +18,13 @@ package glib
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"strings"
 
 	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/util"
 )
 
 // ListGroups returns a list of all current groups from the API
@@ -81,6 +84,50 @@ func (ds *DirectoryService) UpdateGroup(ctx context.Context, oldGroup *directory
 	return updatedGroup, nil
 }
 
+// GetGroupAliases returns the editable aliases of a group
+func (ds *DirectoryService) GetGroupAliases(ctx context.Context, group *directoryv1.Group) ([]string, error) {
+	data, err := ds.Groups.Aliases.List(group.Email).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list group aliases: %v", err)
+	}
+
+	aliases := aliases{}
+	if err := util.ConvertToStruct(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse group aliases: %v", err)
+	}
+
+	result := []string{}
+	for _, alias := range aliases.Aliases {
+		result = append(result, alias.Alias)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// CreateGroupAlias adds an alias to a group
+func (ds *DirectoryService) CreateGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error {
+	newAlias := &directoryv1.Alias{
+		Alias: alias,
+	}
+
+	if _, err := ds.Groups.Aliases.Insert(group.Email, newAlias).Context(ctx).Do(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteGroupAlias removes an alias from a group
+func (ds *DirectoryService) DeleteGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error {
+	if err := ds.Groups.Aliases.Delete(group.Email, alias).Context(ctx).Do(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ListMembers returns a list of all current group members form the API
 func (ds *DirectoryService) ListMembers(ctx context.Context, group *directoryv1.Group) ([]*directoryv1.Member, error) {
 	members := []*directoryv1.Member{}
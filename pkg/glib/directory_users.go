@@ -61,6 +61,14 @@ func (ds *DirectoryService) ListUsers(ctx context.Context) ([]*directoryv1.User,
 	return users, nil
 }
 
+func (ds *DirectoryService) GetUser(ctx context.Context, email string) (*directoryv1.User, error) {
+	return ds.Users.Get(email).
+		Projection("custom").
+		CustomFieldMask(config.SchemaName).
+		Context(ctx).
+		Do()
+}
+
 func (ds *DirectoryService) CreateUser(ctx context.Context, user *directoryv1.User) (*directoryv1.User, error) {
 	// generate a rand password
 	pass, err := password.Generate(20, 5, 5, false, false)
@@ -91,7 +99,7 @@ func (ds *DirectoryService) DeleteUser(ctx context.Context, user *directoryv1.Us
 func (ds *DirectoryService) UpdateUser(ctx context.Context, oldUser *directoryv1.User, newUser *directoryv1.User) (*directoryv1.User, error) {
 	// google.golang.org/api v0.40.0 cannot by default handle removing recovery phone/email
 	// fields, see https://github.com/googleapis/google-api-go-client/issues/901
-	newUser.ForceSendFields = []string{"RecoveryEmail", "RecoveryPhone"}
+	newUser.ForceSendFields = append(newUser.ForceSendFields, "RecoveryEmail", "RecoveryPhone")
 
 	updatedUser, err := ds.Users.Update(oldUser.PrimaryEmail, newUser).Context(ctx).Do()
 	if err != nil {
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+
+	datatransferv1 "google.golang.org/api/admin/datatransfer/v1"
+)
+
+func (h *Handler) serveDataTransfer(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	ws := h.Workspace
+
+	switch {
+	// applications
+	case len(parts) == 1 && parts[0] == "applications" && r.Method == http.MethodGet:
+		applications := ws.DataTransferApplications()
+
+		start, end, next, err := h.paginate(r, len(applications))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", &datatransferv1.ApplicationsListResponse{
+			Kind:          "admin#datatransfer#applicationsList",
+			Applications:  applications[start:end],
+			NextPageToken: next,
+		})
+
+	// transfers
+	case len(parts) == 1 && parts[0] == "transfers" && r.Method == http.MethodPost:
+		transfer := &datatransferv1.DataTransfer{}
+		if err := decodeBody(r, transfer); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		created, err := ws.InsertTransfer(ctx, transfer)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", created)
+
+	// transfers/{dataTransferId}
+	case len(parts) == 2 && parts[0] == "transfers" && r.Method == http.MethodGet:
+		transfer, err := ws.GetTransfer(ctx, parts[1])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", transfer)
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
@@ -0,0 +1,659 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+	"strings"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+)
+
+func (h *Handler) serveDirectory(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		writeError(w, notFound("unknown endpoint %s", r.URL.Path))
+		return
+	}
+
+	switch {
+	case parts[0] == "users":
+		h.serveUsers(w, r, parts[1:])
+	case parts[0] == "groups":
+		h.serveGroups(w, r, parts[1:])
+	case parts[0] == "customers" && len(parts) == 2 && r.Method == http.MethodGet:
+		h.serveCustomer(w, r, parts[1])
+	case parts[0] == "customer" && len(parts) >= 3 && parts[2] == "orgunits":
+		h.serveOrgUnits(w, r, parts[3:])
+	case parts[0] == "customer" && len(parts) >= 3 && parts[2] == "schemas":
+		h.serveSchemas(w, r, parts[3:])
+	default:
+		writeError(w, notFound("unknown endpoint %s", r.URL.Path))
+	}
+}
+
+func (h *Handler) serveCustomer(w http.ResponseWriter, r *http.Request, customerKey string) {
+	customerID, err := h.Workspace.CustomerID(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if customerKey != "my_customer" && customerKey != customerID {
+		writeError(w, notFound("unknown customer %s", customerKey))
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "", &directoryv1.Customer{
+		Kind: "admin#directory#customer",
+		Id:   customerID,
+	})
+}
+
+func (h *Handler) serveUsers(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	ws := h.Workspace
+
+	switch {
+	// users
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		users, err := ws.ListUsers(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		start, end, next, err := h.paginate(r, len(users))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", &directoryv1.Users{
+			Kind:          "admin#directory#users",
+			Users:         users[start:end],
+			NextPageToken: next,
+		})
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		user := &directoryv1.User{}
+		if err := decodeBody(r, user); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		created, err := ws.CreateUser(ctx, user)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, created.Etag, created)
+
+	// users/{userKey}
+	case len(parts) == 1:
+		current, err := ws.GetUser(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			if err := checkPrecondition(r, current.Etag); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeResponse(w, r, http.StatusOK, current.Etag, current)
+
+		case http.MethodPut, http.MethodPatch:
+			user := &directoryv1.User{}
+			if err := decodeBody(r, user); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			updated, err := ws.UpdateUser(ctx, current, user)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, updated.Etag, updated)
+
+		case http.MethodDelete:
+			if err := ws.DeleteUser(ctx, current); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeNoContent(w)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	// users/{userKey}/aliases
+	case len(parts) == 2 && parts[1] == "aliases":
+		current, err := ws.GetUser(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			aliases, err := ws.GetUserAliases(ctx, current)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			result := &directoryv1.Aliases{
+				Kind:    "admin#directory#aliases",
+				Aliases: []interface{}{},
+			}
+
+			for _, alias := range aliases {
+				result.Aliases = append(result.Aliases, &directoryv1.Alias{
+					Kind:         "admin#directory#alias",
+					Alias:        alias,
+					Id:           current.Id,
+					PrimaryEmail: current.PrimaryEmail,
+				})
+			}
+
+			writeResponse(w, r, http.StatusOK, "", result)
+
+		case http.MethodPost:
+			alias := &directoryv1.Alias{}
+			if err := decodeBody(r, alias); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if err := ws.CreateUserAlias(ctx, current, alias.Alias); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, "", &directoryv1.Alias{
+				Kind:         "admin#directory#alias",
+				Alias:        alias.Alias,
+				Id:           current.Id,
+				PrimaryEmail: current.PrimaryEmail,
+			})
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	// users/{userKey}/aliases/{alias}
+	case len(parts) == 3 && parts[1] == "aliases" && r.Method == http.MethodDelete:
+		current, err := ws.GetUser(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if err := ws.DeleteUserAlias(ctx, current, parts[2]); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeNoContent(w)
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *Handler) serveGroups(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	ws := h.Workspace
+
+	switch {
+	// groups
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		groups, err := ws.ListGroups(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		start, end, next, err := h.paginate(r, len(groups))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", &directoryv1.Groups{
+			Kind:          "admin#directory#groups",
+			Groups:        groups[start:end],
+			NextPageToken: next,
+		})
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		group := &directoryv1.Group{}
+		if err := decodeBody(r, group); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		created, err := ws.CreateGroup(ctx, group)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, created.Etag, created)
+
+	// groups/{groupKey}
+	case len(parts) == 1:
+		current, err := ws.GetGroup(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			if err := checkPrecondition(r, current.Etag); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeResponse(w, r, http.StatusOK, current.Etag, current)
+
+		case http.MethodPut, http.MethodPatch:
+			group := &directoryv1.Group{}
+			if err := decodeBody(r, group); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			updated, err := ws.UpdateGroup(ctx, current, group)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, updated.Etag, updated)
+
+		case http.MethodDelete:
+			if err := ws.DeleteGroup(ctx, current); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeNoContent(w)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	// groups/{groupKey}/aliases
+	case len(parts) == 2 && parts[1] == "aliases":
+		current, err := ws.GetGroup(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			aliases, err := ws.GetGroupAliases(ctx, current)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			result := &directoryv1.Aliases{
+				Kind:    "admin#directory#aliases",
+				Aliases: []interface{}{},
+			}
+
+			for _, alias := range aliases {
+				result.Aliases = append(result.Aliases, &directoryv1.Alias{
+					Kind:         "admin#directory#alias",
+					Alias:        alias,
+					Id:           current.Id,
+					PrimaryEmail: current.Email,
+				})
+			}
+
+			writeResponse(w, r, http.StatusOK, "", result)
+
+		case http.MethodPost:
+			alias := &directoryv1.Alias{}
+			if err := decodeBody(r, alias); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if err := ws.CreateGroupAlias(ctx, current, alias.Alias); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, "", &directoryv1.Alias{
+				Kind:         "admin#directory#alias",
+				Alias:        alias.Alias,
+				Id:           current.Id,
+				PrimaryEmail: current.Email,
+			})
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	// groups/{groupKey}/aliases/{alias}
+	case len(parts) == 3 && parts[1] == "aliases" && r.Method == http.MethodDelete:
+		current, err := ws.GetGroup(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if err := ws.DeleteGroupAlias(ctx, current, parts[2]); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeNoContent(w)
+
+	// groups/{groupKey}/members
+	case len(parts) == 2 && parts[1] == "members":
+		current, err := ws.GetGroup(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			members, err := ws.ListMembers(ctx, current)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			start, end, next, err := h.paginate(r, len(members))
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, "", &directoryv1.Members{
+				Kind:          "admin#directory#members",
+				Members:       members[start:end],
+				NextPageToken: next,
+			})
+
+		case http.MethodPost:
+			member := &directoryv1.Member{}
+			if err := decodeBody(r, member); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if err := ws.AddNewMember(ctx, current, member); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			// CUSTOMER members have no email
+			key := member.Email
+			if key == "" {
+				key = member.Id
+			}
+
+			created, err := ws.GetMember(ctx, current, key)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, created.Etag, created)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	// groups/{groupKey}/members/{memberKey}
+	case len(parts) == 3 && parts[1] == "members":
+		group, err := ws.GetGroup(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		current, err := ws.GetMember(ctx, group, parts[2])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			if err := checkPrecondition(r, current.Etag); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeResponse(w, r, http.StatusOK, current.Etag, current)
+
+		case http.MethodPut, http.MethodPatch:
+			member := &directoryv1.Member{}
+			if err := decodeBody(r, member); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			member.Id = current.Id
+			if err := ws.UpdateMembership(ctx, group, member); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			updated, err := ws.GetMember(ctx, group, current.Id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, updated.Etag, updated)
+
+		case http.MethodDelete:
+			if err := ws.RemoveMember(ctx, group, current); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeNoContent(w)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *Handler) serveOrgUnits(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	ws := h.Workspace
+
+	// org units are addressed by their full path (which can contain slashes) or ID
+	key := strings.Join(parts, "/")
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		orgUnits, err := ws.ListOrgUnits(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		// org units are not paginated by the real API either
+		writeResponse(w, r, http.StatusOK, "", &directoryv1.OrgUnits{
+			Kind:              "admin#directory#orgUnits",
+			OrganizationUnits: orgUnits,
+		})
+
+	case key == "" && r.Method == http.MethodPost:
+		orgUnit := &directoryv1.OrgUnit{}
+		if err := decodeBody(r, orgUnit); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if err := ws.CreateOrgUnit(ctx, orgUnit); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		parent := strings.TrimSuffix(orgUnit.ParentOrgUnitPath, "/")
+		created, err := ws.GetOrgUnit(ctx, parent+"/"+orgUnit.Name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, created.Etag, created)
+
+	case key != "":
+		current, err := ws.GetOrgUnit(ctx, key)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			if err := checkPrecondition(r, current.Etag); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeResponse(w, r, http.StatusOK, current.Etag, current)
+
+		case http.MethodPut, http.MethodPatch:
+			orgUnit := &directoryv1.OrgUnit{}
+			if err := decodeBody(r, orgUnit); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if err := ws.UpdateOrgUnit(ctx, current, orgUnit); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			updated, err := ws.GetOrgUnit(ctx, current.OrgUnitId)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, updated.Etag, updated)
+
+		case http.MethodDelete:
+			if err := ws.DeleteOrgUnit(ctx, current); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeNoContent(w)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *Handler) serveSchemas(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	ws := h.Workspace
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		schema := &directoryv1.Schema{}
+		if err := decodeBody(r, schema); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		created, err := ws.CreateSchema(ctx, schema)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, created.Etag, created)
+
+	case len(parts) == 1:
+		current, err := ws.GetSchema(ctx, parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeResponse(w, r, http.StatusOK, current.Etag, current)
+
+		case http.MethodPut, http.MethodPatch:
+			if err := checkPrecondition(r, current.Etag); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			schema := &directoryv1.Schema{}
+			if err := decodeBody(r, schema); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			updated, err := ws.UpdateSchema(ctx, current, schema)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, updated.Etag, updated)
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
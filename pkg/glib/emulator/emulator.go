@@ -0,0 +1,270 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package emulator serves the REST endpoints of the Admin SDK Directory and
+// Data Transfer, Enterprise License Manager and Groups Settings APIs that
+// GMan uses, backed by an in-memory fake Workspace. Point GMan's
+// -api-endpoint flag to the server's URL to run it against the emulator.
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+const (
+	directoryPrefix      = "/admin/directory/v1/"
+	dataTransferPrefix   = "/admin/datatransfer/v1/"
+	licensingPrefix      = "/apps/licensing/v1/"
+	groupsSettingsPrefix = "/groups/v1/groups/"
+
+	// DefaultPageSize is the number of items returned per page when
+	// the client does not specify maxResults.
+	DefaultPageSize = 100
+)
+
+// Handler is an http.Handler that implements the Google APIs.
+type Handler struct {
+	Workspace *fake.Workspace
+
+	// PageSize is the maximum number of items per page; setting this to
+	// a small value helps to exercise pagination.
+	PageSize int
+}
+
+// NewHandler returns a handler that serves the given workspace.
+func NewHandler(workspace *fake.Workspace) *Handler {
+	return &Handler{
+		Workspace: workspace,
+		PageSize:  DefaultPageSize,
+	}
+}
+
+// NewServer starts a new httptest server for the given workspace. Callers
+// must close the server once they are done.
+func NewServer(workspace *fake.Workspace) *httptest.Server {
+	return httptest.NewServer(NewHandler(workspace))
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	switch {
+	case strings.HasPrefix(path, directoryPrefix):
+		h.serveDirectory(w, r, splitPath(strings.TrimPrefix(path, directoryPrefix)))
+	case strings.HasPrefix(path, dataTransferPrefix):
+		h.serveDataTransfer(w, r, splitPath(strings.TrimPrefix(path, dataTransferPrefix)))
+	case strings.HasPrefix(path, licensingPrefix):
+		h.serveLicensing(w, r, splitPath(strings.TrimPrefix(path, licensingPrefix)))
+	case strings.HasPrefix(path, groupsSettingsPrefix):
+		h.serveGroupsSettings(w, r, splitPath(strings.TrimPrefix(path, groupsSettingsPrefix)))
+	default:
+		writeError(w, notFound("unknown endpoint %s", path))
+	}
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &googleapi.Error{
+		Code:    http.StatusNotFound,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func methodNotAllowed(r *http.Request) error {
+	return &googleapi.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: fmt.Sprintf("method %s is not supported for %s", r.Method, r.URL.Path),
+	}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &googleapi.Error{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+var reasons = map[int]string{
+	http.StatusBadRequest:         "invalid",
+	http.StatusNotFound:           "notFound",
+	http.StatusConflict:           "duplicate",
+	http.StatusPreconditionFailed: "conditionNotMet",
+}
+
+// writeError writes an error response in the format used by Google APIs,
+// so that the client libraries turn it back into a *googleapi.Error.
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := &googleapi.Error{}
+	if !errors.As(err, &apiErr) {
+		apiErr = &googleapi.Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+
+	reason := reasons[apiErr.Code]
+	if reason == "" {
+		reason = "backendError"
+	}
+
+	body := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    apiErr.Code,
+			"message": apiErr.Message,
+			"errors": []map[string]string{{
+				"domain":  "global",
+				"reason":  reason,
+				"message": apiErr.Message,
+			}},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(apiErr.Code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeResponse encodes the given API object. If etag is not empty, it is
+// sent as the ETag header and conditional GET requests are honoured.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, etag string, data interface{}) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+
+		if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(encoded)
+}
+
+func writeNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPrecondition verifies the If-Match header of mutating requests
+// against the current ETag of the resource.
+func checkPrecondition(r *http.Request, currentEtag string) error {
+	expected := r.Header.Get("If-Match")
+	if expected == "" || expected == "*" || expected == currentEtag {
+		return nil
+	}
+
+	return &googleapi.Error{
+		Code:    http.StatusPreconditionFailed,
+		Message: fmt.Sprintf("resource has been modified (current ETag is %s)", currentEtag),
+	}
+}
+
+// decodeBody decodes a request body into a generated API type and marks
+// every field that was present in the JSON as ForceSendFields, so that
+// explicitly emptied fields survive when the fake re-encodes the object.
+func decodeBody(r *http.Request, dst interface{}) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+
+	encoded, _ := json.Marshal(raw)
+	if err := json.Unmarshal(encoded, dst); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+
+	value := reflect.ValueOf(dst).Elem()
+	forceSend := value.FieldByName("ForceSendFields")
+	if !forceSend.IsValid() {
+		return nil
+	}
+
+	fields := []string{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if _, ok := raw[name]; ok && name != "" && name != "-" {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	forceSend.Set(reflect.ValueOf(fields))
+
+	return nil
+}
+
+// paginate determines the slice of items to return for the current
+// request and the token for the next page, if any.
+func (h *Handler) paginate(r *http.Request, total int) (int, int, string, error) {
+	pageSize := h.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	if maxResults := r.URL.Query().Get("maxResults"); maxResults != "" {
+		size, err := strconv.Atoi(maxResults)
+		if err != nil || size <= 0 {
+			return 0, 0, "", badRequest("invalid maxResults %q", maxResults)
+		}
+
+		if size < pageSize {
+			pageSize = size
+		}
+	}
+
+	start := 0
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		offset, err := strconv.Atoi(token)
+		if err != nil || offset < 0 || offset > total {
+			return 0, 0, "", badRequest("invalid pageToken %q", token)
+		}
+
+		start = offset
+	}
+
+	end := start + pageSize
+	if end >= total {
+		return start, total, "", nil
+	}
+
+	return start, end, strconv.Itoa(end), nil
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+const testOrganization = "example"
+
+func TestMain(m *testing.M) {
+	// fetching the license status logs every license
+	log.SetOutput(io.Discard)
+
+	os.Exit(m.Run())
+}
+
+// newTestServer serves the workspace with a small page size, so that the
+// clients have to follow page tokens.
+func newTestServer(t *testing.T, ws *fake.Workspace) string {
+	t.Helper()
+
+	handler := NewHandler(ws)
+	handler.PageSize = 2
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server.URL + "/"
+}
+
+func TestDirectoryUsers(t *testing.T) {
+	ctx := context.Background()
+	ws := fake.NewWorkspace(testOrganization, config.AllLicenses)
+
+	directorySrv, err := glib.NewDirectoryService(ctx, testOrganization, "", "", newTestServer(t, ws), 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to create directory client: %v", err)
+	}
+
+	expected := []string{}
+	for i := 1; i <= 5; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		expected = append(expected, email)
+
+		if _, err := directorySrv.CreateUser(ctx, &directoryv1.User{
+			PrimaryEmail: email,
+			Name: &directoryv1.UserName{
+				GivenName:  "User",
+				FamilyName: fmt.Sprintf("%d", i),
+			},
+		}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	users, err := directorySrv.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+
+	emails := []string{}
+	for _, user := range users {
+		emails = append(emails, user.PrimaryEmail)
+	}
+
+	if !reflect.DeepEqual(emails, expected) {
+		t.Fatalf("Expected users %v, but got %v", expected, emails)
+	}
+
+	if err := directorySrv.CreateUserAlias(ctx, users[0], "alias@example.com"); err != nil {
+		t.Fatalf("Failed to create alias: %v", err)
+	}
+
+	aliases, err := directorySrv.GetUserAliases(ctx, users[0])
+	if err != nil {
+		t.Fatalf("Failed to list aliases: %v", err)
+	}
+
+	if !reflect.DeepEqual(aliases, []string{"alias@example.com"}) {
+		t.Fatalf("Expected alias@example.com, but got %v", aliases)
+	}
+
+	// errors from the fake are returned as API errors
+	_, err = directorySrv.CreateUser(ctx, &directoryv1.User{
+		PrimaryEmail: "alias@example.com",
+		Name:         &directoryv1.UserName{GivenName: "Jane", FamilyName: "Doe"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Error 409") {
+		t.Fatalf("Expected a conflict, but got %v", err)
+	}
+}
+
+func TestGroupsAndSettings(t *testing.T) {
+	ctx := context.Background()
+	ws := fake.NewWorkspace(testOrganization, config.AllLicenses)
+	endpoint := newTestServer(t, ws)
+
+	directorySrv, err := glib.NewDirectoryService(ctx, testOrganization, "", "", endpoint, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to create directory client: %v", err)
+	}
+
+	groupsSettingsSrv, err := glib.NewGroupsSettingsService(ctx, "", "", endpoint, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to create groups settings client: %v", err)
+	}
+
+	group, err := directorySrv.CreateGroup(ctx, &directoryv1.Group{Name: "Team", Email: "team@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+
+	for _, email := range []string{"c@example.com", "a@example.com", "b@example.com"} {
+		if err := directorySrv.AddNewMember(ctx, group, &directoryv1.Member{Email: email, Role: "MEMBER"}); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	members, err := directorySrv.ListMembers(ctx, group)
+	if err != nil {
+		t.Fatalf("Failed to list members: %v", err)
+	}
+
+	if len(members) != 3 {
+		t.Fatalf("Expected 3 members, but got %d", len(members))
+	}
+
+	if _, err := groupsSettingsSrv.UpdateSettings(ctx, group, &groupssettingsv1.Groups{WhoCanJoin: "INVITED_CAN_JOIN"}); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+
+	settings, err := groupsSettingsSrv.GetSettings(ctx, group.Email)
+	if err != nil {
+		t.Fatalf("Failed to get settings: %v", err)
+	}
+
+	if settings.WhoCanJoin != "INVITED_CAN_JOIN" {
+		t.Fatalf("Expected whoCanJoin to be INVITED_CAN_JOIN, but got %q", settings.WhoCanJoin)
+	}
+
+	for _, alias := range []string{"crew@example.com", "staff@example.com"} {
+		if err := directorySrv.CreateGroupAlias(ctx, group, alias); err != nil {
+			t.Fatalf("Failed to create alias: %v", err)
+		}
+	}
+
+	if err := directorySrv.DeleteGroupAlias(ctx, group, "crew@example.com"); err != nil {
+		t.Fatalf("Failed to delete alias: %v", err)
+	}
+
+	aliases, err := directorySrv.GetGroupAliases(ctx, group)
+	if err != nil {
+		t.Fatalf("Failed to get aliases: %v", err)
+	}
+
+	if expected := []string{"staff@example.com"}; !reflect.DeepEqual(aliases, expected) {
+		t.Fatalf("Expected aliases %v, but got %v", expected, aliases)
+	}
+
+	customerID, err := directorySrv.CustomerID(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get customer ID: %v", err)
+	}
+
+	if customerID != fake.CustomerID {
+		t.Fatalf("Expected customer ID %q, but got %q", fake.CustomerID, customerID)
+	}
+}
+
+func TestLicensing(t *testing.T) {
+	ctx := context.Background()
+	ws := fake.NewWorkspace(testOrganization, config.AllLicenses)
+	endpoint := newTestServer(t, ws)
+
+	directorySrv, err := glib.NewDirectoryService(ctx, testOrganization, "", "", endpoint, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to create directory client: %v", err)
+	}
+
+	licensingSrv, err := glib.NewLicensingService(ctx, testOrganization, "", "", endpoint, 0, nil, config.AllLicenses)
+	if err != nil {
+		t.Fatalf("Failed to create licensing client: %v", err)
+	}
+
+	user, err := directorySrv.CreateUser(ctx, &directoryv1.User{
+		PrimaryEmail: "jane@example.com",
+		Name:         &directoryv1.UserName{GivenName: "Jane", FamilyName: "Doe"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	license := config.AllLicenses[0]
+	if err := licensingSrv.AssignLicense(ctx, user, license); err != nil {
+		t.Fatalf("Failed to assign license: %v", err)
+	}
+
+	status, err := glib.GetLicenseStatus(ctx, licensingSrv)
+	if err != nil {
+		t.Fatalf("Failed to fetch license status: %v", err)
+	}
+
+	licenses := status.GetLicensesForUser(user)
+	if len(licenses) != 1 || licenses[0].Name != license.Name {
+		t.Fatalf("Expected %s to be assigned, but got %v", license.Name, licenses)
+	}
+
+	if err := licensingSrv.UnassignLicense(ctx, user, license); err != nil {
+		t.Fatalf("Failed to unassign license: %v", err)
+	}
+
+	usages, err := licensingSrv.LicenseUsages(ctx, license)
+	if err != nil {
+		t.Fatalf("Failed to list license usages: %v", err)
+	}
+
+	if len(usages) > 0 {
+		t.Fatalf("Expected no license assignments, but got %v", usages)
+	}
+}
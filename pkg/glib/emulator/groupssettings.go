@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+)
+
+func (h *Handler) serveGroupsSettings(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 1 {
+		writeError(w, notFound("unknown endpoint %s", r.URL.Path))
+		return
+	}
+
+	ctx := r.Context()
+
+	group, err := h.Workspace.GetGroup(ctx, parts[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err := h.Workspace.GetSettings(ctx, group.Email)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", settings)
+
+	case http.MethodPut, http.MethodPatch:
+		settings := &groupssettingsv1.Groups{}
+		if err := decodeBody(r, settings); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		updated, err := h.Workspace.UpdateSettings(ctx, group, settings)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", updated)
+
+	default:
+		writeError(w, methodNotAllowed(r))
+	}
+}
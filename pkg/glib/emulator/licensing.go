@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"net/http"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/licensing/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func (h *Handler) serveLicensing(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) < 3 || parts[0] != "product" {
+		writeError(w, notFound("unknown endpoint %s", r.URL.Path))
+		return
+	}
+
+	productID := parts[1]
+
+	switch {
+	// product/{productId}/users
+	case len(parts) == 3 && parts[2] == "users" && r.Method == http.MethodGet:
+		h.listLicenseAssignments(w, r, productID, "")
+
+	// product/{productId}/sku/{skuId}/users
+	case len(parts) == 5 && parts[2] == "sku" && parts[4] == "users" && r.Method == http.MethodGet:
+		h.listLicenseAssignments(w, r, productID, parts[3])
+
+	// product/{productId}/sku/{skuId}/user
+	case len(parts) == 5 && parts[2] == "sku" && parts[4] == "user" && r.Method == http.MethodPost:
+		license, err := h.findLicense(productID, parts[3])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		insert := &licensing.LicenseAssignmentInsert{}
+		if err := decodeBody(r, insert); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		user := &directoryv1.User{PrimaryEmail: insert.UserId}
+		if err := h.Workspace.AssignLicense(r.Context(), user, *license); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, "", toAssignment(*license, insert.UserId))
+
+	// product/{productId}/sku/{skuId}/user/{userId}
+	case len(parts) == 6 && parts[2] == "sku" && parts[4] == "user":
+		license, err := h.findLicense(productID, parts[3])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		userID := parts[5]
+
+		switch r.Method {
+		case http.MethodGet:
+			assigned, err := h.Workspace.LicenseUsages(r.Context(), *license)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			for _, email := range assigned {
+				if email == userID {
+					writeResponse(w, r, http.StatusOK, "", toAssignment(*license, email))
+					return
+				}
+			}
+
+			writeError(w, notFound("user %q does not have license %q", userID, license.SkuId))
+
+		case http.MethodDelete:
+			user := &directoryv1.User{PrimaryEmail: userID}
+			if err := h.Workspace.UnassignLicense(r.Context(), user, *license); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			writeResponse(w, r, http.StatusOK, "", &licensing.Empty{})
+
+		default:
+			writeError(w, methodNotAllowed(r))
+		}
+
+	default:
+		writeError(w, notFound("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *Handler) findLicense(productID string, skuID string) (*config.License, error) {
+	licenses, err := h.Workspace.GetLicenses()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, license := range licenses {
+		if license.ProductId == productID && license.SkuId == skuID {
+			return &licenses[i], nil
+		}
+	}
+
+	return nil, notFound("unknown product %q / SKU %q", productID, skuID)
+}
+
+func (h *Handler) listLicenseAssignments(w http.ResponseWriter, r *http.Request, productID string, skuID string) {
+	licenses, err := h.Workspace.GetLicenses()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	assignments := []*licensing.LicenseAssignment{}
+	for _, license := range licenses {
+		if license.ProductId != productID || (skuID != "" && license.SkuId != skuID) {
+			continue
+		}
+
+		emails, err := h.Workspace.LicenseUsages(r.Context(), license)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for _, email := range emails {
+			assignments = append(assignments, toAssignment(license, email))
+		}
+	}
+
+	start, end, next, err := h.paginate(r, len(assignments))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "", &licensing.LicenseAssignmentList{
+		Kind:          "licensing#licenseAssignmentList",
+		Items:         assignments[start:end],
+		NextPageToken: next,
+	})
+}
+
+func toAssignment(license config.License, userID string) *licensing.LicenseAssignment {
+	return &licensing.LicenseAssignment{
+		Kind:      "licensing#licenseAssignment",
+		ProductId: license.ProductId,
+		SkuId:     license.SkuId,
+		SkuName:   license.Name,
+		UserId:    userID,
+	}
+}
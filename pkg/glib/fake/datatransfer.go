@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"time"
+
+	datatransferv1 "google.golang.org/api/admin/datatransfer/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/glib"
+)
+
+// transferApplications are the applications that support data transfers,
+// with the same IDs that GSuite uses.
+var transferApplications = []*datatransferv1.Application{
+	{
+		Kind: "admin#datatransfer#ApplicationResource",
+		Id:   55656082996,
+		Name: glib.DriveApplication,
+	},
+	{
+		Kind: "admin#datatransfer#ApplicationResource",
+		Id:   435070579839,
+		Name: glib.CalendarApplication,
+	},
+}
+
+// DataTransferApplications returns all applications whose data can be
+// transferred.
+func (w *Workspace) DataTransferApplications() []*datatransferv1.Application {
+	result := []*datatransferv1.Application{}
+	for _, application := range transferApplications {
+		copied := &datatransferv1.Application{}
+		clone(application, copied)
+		result = append(result, copied)
+	}
+
+	return result
+}
+
+// InsertTransfer starts a new data transfer. Transfers are not actually
+// performed, but complete the first time they are looked at.
+func (w *Workspace) InsertTransfer(ctx context.Context, transfer *datatransferv1.DataTransfer) (*datatransferv1.DataTransfer, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.findUser(transfer.OldOwnerUserId) == nil {
+		return nil, notFound("user %q does not exist", transfer.OldOwnerUserId)
+	}
+
+	if w.findUser(transfer.NewOwnerUserId) == nil {
+		return nil, notFound("user %q does not exist", transfer.NewOwnerUserId)
+	}
+
+	if len(transfer.ApplicationDataTransfers) == 0 {
+		return nil, badRequest("no applications given")
+	}
+
+	created := &datatransferv1.DataTransfer{}
+	clone(transfer, created)
+
+	created.Id = w.nextID()
+	created.Etag = w.nextEtag()
+	created.Kind = "admin#datatransfer#DataTransfer"
+	created.RequestTime = time.Now().UTC().Format(time.RFC3339)
+	created.OverallTransferStatusCode = "inProgress"
+
+	for _, application := range created.ApplicationDataTransfers {
+		application.ApplicationTransferStatus = "inProgress"
+	}
+
+	w.transfers = append(w.transfers, created)
+
+	result := &datatransferv1.DataTransfer{}
+	clone(created, result)
+
+	return result, nil
+}
+
+func (w *Workspace) TransferUserData(ctx context.Context, oldOwnerID string, newOwnerID string) (*datatransferv1.DataTransfer, error) {
+	transfer := &datatransferv1.DataTransfer{
+		OldOwnerUserId: oldOwnerID,
+		NewOwnerUserId: newOwnerID,
+	}
+
+	for _, application := range transferApplications {
+		transfer.ApplicationDataTransfers = append(transfer.ApplicationDataTransfers, &datatransferv1.ApplicationDataTransfer{
+			ApplicationId: application.Id,
+		})
+	}
+
+	return w.InsertTransfer(ctx, transfer)
+}
+
+func (w *Workspace) GetTransfer(ctx context.Context, transferID string) (*datatransferv1.DataTransfer, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for _, transfer := range w.transfers {
+		if transfer.Id == transferID {
+			transfer.OverallTransferStatusCode = glib.TransferCompleted
+			for _, application := range transfer.ApplicationDataTransfers {
+				application.ApplicationTransferStatus = glib.TransferCompleted
+			}
+
+			result := &datatransferv1.DataTransfer{}
+			clone(transfer, result)
+
+			return result, nil
+		}
+	}
+
+	return nil, notFound("transfer %q does not exist", transferID)
+}
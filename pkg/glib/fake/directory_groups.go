@@ -0,0 +1,455 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+var (
+	groupReadOnlyFields  = []string{"id", "etag", "kind", "aliases", "nonEditableAliases", "adminCreated", "directMembersCount"}
+	memberReadOnlyFields = []string{"id", "etag", "kind", "email", "type", "status"}
+)
+
+// findGroup returns the group with the given ID or email.
+func (w *Workspace) findGroup(key string) *directoryv1.Group {
+	for _, group := range w.groups {
+		if group.Id == key || sameEmail(group.Email, key) {
+			return group
+		}
+
+		for _, alias := range group.Aliases {
+			if sameEmail(alias, key) {
+				return group
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) publicGroup(group *directoryv1.Group) *directoryv1.Group {
+	result := &directoryv1.Group{}
+	clone(group, result)
+	result.DirectMembersCount = int64(len(w.members[group.Id]))
+
+	return result
+}
+
+func (w *Workspace) ListGroups(ctx context.Context) ([]*directoryv1.Group, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	groups := []*directoryv1.Group{}
+	for _, group := range w.groups {
+		groups = append(groups, w.publicGroup(group))
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+
+	return groups, nil
+}
+
+func (w *Workspace) GetGroup(ctx context.Context, key string) (*directoryv1.Group, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	group := w.findGroup(key)
+	if group == nil {
+		return nil, notFound("group %q does not exist", key)
+	}
+
+	return w.publicGroup(group), nil
+}
+
+func (w *Workspace) CreateGroup(ctx context.Context, group *directoryv1.Group) (*directoryv1.Group, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if group.Email == "" {
+		return nil, badRequest("group email is required")
+	}
+
+	if w.emailTaken(group.Email) {
+		return nil, conflict("entity %q already exists", group.Email)
+	}
+
+	created := &directoryv1.Group{}
+	overlay(&directoryv1.Group{}, group, created, groupReadOnlyFields...)
+
+	if created.Name == "" {
+		created.Name = created.Email
+	}
+
+	created.Id = w.nextID()
+	created.Etag = w.nextEtag()
+	created.Kind = "admin#directory#group"
+	created.AdminCreated = true
+
+	w.groups = append(w.groups, created)
+	w.members[created.Id] = []*directoryv1.Member{}
+	w.settings[created.Id] = defaultSettings(created)
+
+	return w.publicGroup(created), nil
+}
+
+func (w *Workspace) DeleteGroup(ctx context.Context, group *directoryv1.Group) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Email)
+	}
+
+	for i, g := range w.groups {
+		if g == stored {
+			w.groups = append(w.groups[:i], w.groups[i+1:]...)
+			break
+		}
+	}
+
+	delete(w.members, stored.Id)
+	delete(w.settings, stored.Id)
+
+	// nested memberships of the deleted group vanish as well
+	for groupID := range w.members {
+		w.removeMemberByID(groupID, stored.Id)
+	}
+
+	return nil
+}
+
+func (w *Workspace) UpdateGroup(ctx context.Context, oldGroup *directoryv1.Group, newGroup *directoryv1.Group) (*directoryv1.Group, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(oldGroup.Email)
+	if stored == nil {
+		return nil, notFound("group %q does not exist", oldGroup.Email)
+	}
+
+	if newGroup.Email != "" && !sameEmail(newGroup.Email, stored.Email) {
+		if owner := w.findGroup(newGroup.Email); (owner != nil && owner != stored) || w.findUser(newGroup.Email) != nil {
+			return nil, conflict("entity %q already exists", newGroup.Email)
+		}
+	}
+
+	updated := &directoryv1.Group{}
+	overlay(stored, newGroup, updated, groupReadOnlyFields...)
+
+	// renaming a group keeps the old address as an alias
+	if !sameEmail(updated.Email, stored.Email) {
+		updated.Aliases = append(removeEmail(updated.Aliases, updated.Email), stored.Email)
+		sort.Strings(updated.Aliases)
+	}
+
+	updated.Etag = w.nextEtag()
+	*stored = *updated
+
+	if settings := w.settings[stored.Id]; settings != nil {
+		settings.Email = stored.Email
+		settings.Name = stored.Name
+		settings.Description = stored.Description
+	}
+
+	return w.publicGroup(stored), nil
+}
+
+func (w *Workspace) GetGroupAliases(ctx context.Context, group *directoryv1.Group) ([]string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return nil, notFound("group %q does not exist", group.Email)
+	}
+
+	result := append([]string{}, stored.Aliases...)
+	sort.Strings(result)
+
+	return result, nil
+}
+
+func (w *Workspace) CreateGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Email)
+	}
+
+	if w.emailTaken(alias) {
+		return conflict("entity %q already exists", alias)
+	}
+
+	stored.Aliases = append(stored.Aliases, alias)
+	sort.Strings(stored.Aliases)
+	stored.Etag = w.nextEtag()
+
+	return nil
+}
+
+func (w *Workspace) DeleteGroupAlias(ctx context.Context, group *directoryv1.Group, alias string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Email)
+	}
+
+	remaining := removeEmail(stored.Aliases, alias)
+	if len(remaining) == len(stored.Aliases) {
+		return notFound("alias %q does not exist", alias)
+	}
+
+	stored.Aliases = remaining
+	stored.Etag = w.nextEtag()
+
+	return nil
+}
+
+// memberEmail returns the current email of the user or group a member
+// refers to, so that renames are reflected in memberships.
+func (w *Workspace) memberEmail(member *directoryv1.Member) string {
+	if user := w.findUser(member.Id); user != nil {
+		return user.PrimaryEmail
+	}
+
+	if group := w.findGroup(member.Id); group != nil {
+		return group.Email
+	}
+
+	return member.Email
+}
+
+func (w *Workspace) findMember(groupID string, key string) *directoryv1.Member {
+	for _, member := range w.members[groupID] {
+		if member.Id == key || sameEmail(w.memberEmail(member), key) {
+			return member
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) removeMemberByID(groupID string, memberID string) bool {
+	members := w.members[groupID]
+
+	for i, member := range members {
+		if member.Id == memberID {
+			w.members[groupID] = append(members[:i], members[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *Workspace) ListMembers(ctx context.Context, group *directoryv1.Group) ([]*directoryv1.Member, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return nil, notFound("group %q does not exist", group.Email)
+	}
+
+	members := []*directoryv1.Member{}
+	for _, member := range w.members[stored.Id] {
+		m := &directoryv1.Member{}
+		clone(member, m)
+		m.Email = w.memberEmail(member)
+
+		members = append(members, m)
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Email < members[j].Email
+	})
+
+	return members, nil
+}
+
+func (w *Workspace) GetMember(ctx context.Context, group *directoryv1.Group, key string) (*directoryv1.Member, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return nil, notFound("group %q does not exist", group.Email)
+	}
+
+	member := w.findMember(stored.Id, key)
+	if member == nil {
+		return nil, notFound("member %q does not exist", key)
+	}
+
+	result := &directoryv1.Member{}
+	clone(member, result)
+	result.Email = w.memberEmail(member)
+
+	return result, nil
+}
+
+// CustomerID returns the fixed ID of the fake customer.
+func (w *Workspace) CustomerID(ctx context.Context) (string, error) {
+	return CustomerID, nil
+}
+
+func (w *Workspace) AddNewMember(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Email)
+	}
+
+	// all users of the customer, identified by the customer ID
+	if member.Type == config.MemberTypeCustomer {
+		if member.Id != CustomerID {
+			return badRequest("invalid customer ID %q", member.Id)
+		}
+
+		if w.findMember(stored.Id, CustomerID) != nil {
+			return conflict("customer is already a member")
+		}
+
+		created := &directoryv1.Member{
+			Id:     CustomerID,
+			Kind:   "admin#directory#member",
+			Role:   member.Role,
+			Status: "ACTIVE",
+			Type:   config.MemberTypeCustomer,
+			Etag:   w.nextEtag(),
+		}
+
+		if created.Role == "" {
+			created.Role = config.MemberRoleMember
+		}
+
+		w.members[stored.Id] = append(w.members[stored.Id], created)
+
+		return nil
+	}
+
+	if member.Email == "" {
+		return badRequest("member email is required")
+	}
+
+	if w.findMember(stored.Id, member.Email) != nil {
+		return conflict("member %q already exists", member.Email)
+	}
+
+	created := &directoryv1.Member{}
+	overlay(&directoryv1.Member{}, member, created, memberReadOnlyFields...)
+
+	created.Email = member.Email
+	created.Kind = "admin#directory#member"
+	created.Status = "ACTIVE"
+	created.Etag = w.nextEtag()
+
+	if created.Role == "" {
+		created.Role = config.MemberRoleMember
+	}
+
+	if created.DeliverySettings == "" {
+		created.DeliverySettings = config.MemberDeliveryDefault
+	}
+
+	if user := w.findUser(member.Email); user != nil {
+		created.Id = user.Id
+		created.Email = user.PrimaryEmail
+		created.Type = "USER"
+	} else if nested := w.findGroup(member.Email); nested != nil {
+		created.Id = nested.Id
+		created.Email = nested.Email
+		created.Type = "GROUP"
+	} else {
+		created.Id = w.nextID()
+		created.Type = "USER"
+	}
+
+	w.members[stored.Id] = append(w.members[stored.Id], created)
+
+	return nil
+}
+
+func (w *Workspace) RemoveMember(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Id)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Id)
+	}
+
+	existing := w.findMember(stored.Id, member.Id)
+	if existing == nil {
+		return notFound("member %q does not exist", member.Id)
+	}
+
+	w.removeMemberByID(stored.Id, existing.Id)
+
+	return nil
+}
+
+func (w *Workspace) UpdateMembership(ctx context.Context, group *directoryv1.Group, member *directoryv1.Member) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Id)
+	if stored == nil {
+		return notFound("group %q does not exist", group.Id)
+	}
+
+	existing := w.findMember(stored.Id, member.Id)
+	if existing == nil {
+		return notFound("member %q does not exist", member.Id)
+	}
+
+	updated := &directoryv1.Member{}
+	overlay(existing, member, updated, memberReadOnlyFields...)
+	updated.Etag = w.nextEtag()
+	*existing = *updated
+
+	return nil
+}
+
+// defaultSettings returns the settings that GSuite applies to new groups.
+func defaultSettings(group *directoryv1.Group) *groupssettingsv1.Groups {
+	_, settings := config.ToGSuiteGroup(&config.Group{GroupSettings: config.DefaultGroupSettings})
+
+	settings.Kind = "groupsSettings#groups"
+	settings.Email = group.Email
+	settings.Name = group.Name
+	settings.Description = group.Description
+
+	return settings
+}
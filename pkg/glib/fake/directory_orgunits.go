@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+)
+
+var orgUnitReadOnlyFields = []string{"orgUnitId", "orgUnitPath", "parentOrgUnitId", "etag", "kind"}
+
+// rootOrgUnit is the implicit top-level org unit. The real API never
+// returns it in listings, but it can be used as a parent or user OU.
+var rootOrgUnit = &directoryv1.OrgUnit{
+	OrgUnitId:   "id:root",
+	OrgUnitPath: "/",
+}
+
+// findOrgUnit returns the org unit with the given path or "id:" prefixed ID.
+func (w *Workspace) findOrgUnit(key string) *directoryv1.OrgUnit {
+	if key == "/" || key == rootOrgUnit.OrgUnitId {
+		return rootOrgUnit
+	}
+
+	if !strings.HasPrefix(key, "id:") && !strings.HasPrefix(key, "/") {
+		key = "/" + key
+	}
+
+	for _, orgUnit := range w.orgUnits {
+		if orgUnit.OrgUnitId == key || strings.EqualFold(orgUnit.OrgUnitPath, key) {
+			return orgUnit
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) ListOrgUnits(ctx context.Context) ([]*directoryv1.OrgUnit, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	orgUnits := []*directoryv1.OrgUnit{}
+	for _, orgUnit := range w.orgUnits {
+		ou := &directoryv1.OrgUnit{}
+		clone(orgUnit, ou)
+
+		orgUnits = append(orgUnits, ou)
+	}
+
+	sort.SliceStable(orgUnits, func(i, j int) bool {
+		return strings.ToLower(orgUnits[i].Name) < strings.ToLower(orgUnits[j].Name)
+	})
+
+	return orgUnits, nil
+}
+
+func (w *Workspace) GetOrgUnit(ctx context.Context, key string) (*directoryv1.OrgUnit, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findOrgUnit(key)
+	if stored == nil || stored == rootOrgUnit {
+		return nil, notFound("org unit %q does not exist", key)
+	}
+
+	result := &directoryv1.OrgUnit{}
+	clone(stored, result)
+
+	return result, nil
+}
+
+func (w *Workspace) CreateOrgUnit(ctx context.Context, orgUnit *directoryv1.OrgUnit) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if orgUnit.Name == "" {
+		return badRequest("org unit name is required")
+	}
+
+	parentPath := orgUnit.ParentOrgUnitPath
+	if parentPath == "" {
+		parentPath = "/"
+	}
+
+	parent := w.findOrgUnit(parentPath)
+	if parent == nil {
+		return badRequest("invalid parent org unit %q", parentPath)
+	}
+
+	created := &directoryv1.OrgUnit{}
+	overlay(&directoryv1.OrgUnit{}, orgUnit, created, orgUnitReadOnlyFields...)
+
+	created.OrgUnitId = "id:" + w.nextID()
+	created.ParentOrgUnitId = parent.OrgUnitId
+	created.ParentOrgUnitPath = parent.OrgUnitPath
+	created.OrgUnitPath = path.Join(parent.OrgUnitPath, created.Name)
+	created.Kind = "admin#directory#orgUnit"
+	created.Etag = w.nextEtag()
+
+	if w.findOrgUnit(created.OrgUnitPath) != nil {
+		return conflict("org unit %q already exists", created.OrgUnitPath)
+	}
+
+	w.orgUnits = append(w.orgUnits, created)
+
+	return nil
+}
+
+func (w *Workspace) DeleteOrgUnit(ctx context.Context, orgUnit *directoryv1.OrgUnit) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findOrgUnit(orgUnit.OrgUnitId)
+	if stored == nil || stored == rootOrgUnit {
+		return notFound("org unit %q does not exist", orgUnit.OrgUnitId)
+	}
+
+	for _, ou := range w.orgUnits {
+		if ou.ParentOrgUnitId == stored.OrgUnitId {
+			return badRequest("org unit %q still has child org units", stored.OrgUnitPath)
+		}
+	}
+
+	for _, user := range w.users {
+		if strings.EqualFold(user.OrgUnitPath, stored.OrgUnitPath) {
+			return badRequest("org unit %q still contains users", stored.OrgUnitPath)
+		}
+	}
+
+	for i, ou := range w.orgUnits {
+		if ou == stored {
+			w.orgUnits = append(w.orgUnits[:i], w.orgUnits[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) UpdateOrgUnit(ctx context.Context, oldUnit *directoryv1.OrgUnit, newUnit *directoryv1.OrgUnit) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findOrgUnit(oldUnit.OrgUnitId)
+	if stored == nil || stored == rootOrgUnit {
+		return notFound("org unit %q does not exist", oldUnit.OrgUnitId)
+	}
+
+	updated := &directoryv1.OrgUnit{}
+	overlay(stored, newUnit, updated, orgUnitReadOnlyFields...)
+
+	parent := w.findOrgUnit(updated.ParentOrgUnitPath)
+	if parent == nil {
+		return badRequest("invalid parent org unit %q", updated.ParentOrgUnitPath)
+	}
+
+	updated.ParentOrgUnitId = parent.OrgUnitId
+	updated.ParentOrgUnitPath = parent.OrgUnitPath
+	updated.OrgUnitPath = path.Join(parent.OrgUnitPath, updated.Name)
+	updated.Etag = w.nextEtag()
+
+	if strings.HasPrefix(updated.OrgUnitPath+"/", stored.OrgUnitPath+"/") && updated.OrgUnitPath != stored.OrgUnitPath {
+		return badRequest("cannot move org unit %q below itself", stored.OrgUnitPath)
+	}
+
+	if existing := w.findOrgUnit(updated.OrgUnitPath); existing != nil && existing != stored {
+		return conflict("org unit %q already exists", updated.OrgUnitPath)
+	}
+
+	if updated.OrgUnitPath != stored.OrgUnitPath {
+		w.movePaths(stored.OrgUnitPath, updated.OrgUnitPath)
+	}
+
+	*stored = *updated
+
+	return nil
+}
+
+// movePaths rewrites the paths of all descendant org units and of
+// users inside them, like GSuite does when an org unit is renamed.
+func (w *Workspace) movePaths(oldPath string, newPath string) {
+	rewrite := func(p string) (string, bool) {
+		if strings.EqualFold(p, oldPath) {
+			return newPath, true
+		}
+
+		if strings.HasPrefix(strings.ToLower(p), strings.ToLower(oldPath)+"/") {
+			return newPath + p[len(oldPath):], true
+		}
+
+		return p, false
+	}
+
+	for _, ou := range w.orgUnits {
+		if p, ok := rewrite(ou.ParentOrgUnitPath); ok {
+			ou.ParentOrgUnitPath = p
+			ou.OrgUnitPath = path.Join(p, ou.Name)
+		}
+	}
+
+	for _, user := range w.users {
+		if p, ok := rewrite(user.OrgUnitPath); ok {
+			user.OrgUnitPath = p
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+)
+
+func (w *Workspace) findSchema(key string) *directoryv1.Schema {
+	for _, schema := range w.schemas {
+		if schema.SchemaId == key || schema.SchemaName == key {
+			return schema
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) GetSchema(ctx context.Context, name string) (*directoryv1.Schema, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findSchema(name)
+	if stored == nil {
+		return nil, notFound("schema %q does not exist", name)
+	}
+
+	result := &directoryv1.Schema{}
+	clone(stored, result)
+
+	return result, nil
+}
+
+func (w *Workspace) CreateSchema(ctx context.Context, schema *directoryv1.Schema) (*directoryv1.Schema, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if schema.SchemaName == "" {
+		return nil, badRequest("schema name is required")
+	}
+
+	if w.findSchema(schema.SchemaName) != nil {
+		return nil, conflict("schema %q already exists", schema.SchemaName)
+	}
+
+	created := &directoryv1.Schema{}
+	clone(schema, created)
+	created.SchemaId = w.nextID()
+	created.Etag = w.nextEtag()
+	created.Kind = "admin#directory#schema"
+
+	w.schemas = append(w.schemas, created)
+
+	result := &directoryv1.Schema{}
+	clone(created, result)
+
+	return result, nil
+}
+
+func (w *Workspace) UpdateSchema(ctx context.Context, oldSchema *directoryv1.Schema, newSchema *directoryv1.Schema) (*directoryv1.Schema, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findSchema(oldSchema.SchemaId)
+	if stored == nil {
+		return nil, notFound("schema %q does not exist", oldSchema.SchemaId)
+	}
+
+	updated := &directoryv1.Schema{}
+	overlay(stored, newSchema, updated, "schemaId", "etag", "kind")
+	updated.Etag = w.nextEtag()
+	*stored = *updated
+
+	result := &directoryv1.Schema{}
+	clone(stored, result)
+
+	return result, nil
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sort"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// userReadOnlyFields are never changed by updates.
+var userReadOnlyFields = []string{"id", "etag", "kind", "aliases", "emails", "customerId", "creationTime", "password", "hashFunction", "customSchemas"}
+
+// findUser returns the user with the given ID, primary email or alias.
+func (w *Workspace) findUser(key string) *directoryv1.User {
+	for _, user := range w.users {
+		if user.Id == key || sameEmail(user.PrimaryEmail, key) {
+			return user
+		}
+
+		for _, alias := range user.Aliases {
+			if sameEmail(alias, key) {
+				return user
+			}
+		}
+	}
+
+	return nil
+}
+
+// publicUser returns a copy of the stored user as the API would return it.
+func (w *Workspace) publicUser(user *directoryv1.User) *directoryv1.User {
+	result := &directoryv1.User{}
+	clone(user, result)
+
+	emails := []directoryv1.UserEmail{{
+		Address: user.PrimaryEmail,
+		Primary: true,
+	}}
+
+	for _, alias := range user.Aliases {
+		emails = append(emails, directoryv1.UserEmail{Address: alias})
+	}
+
+	result.Emails = emails
+	result.CustomerId = CustomerID
+
+	return result
+}
+
+func (w *Workspace) ListUsers(ctx context.Context) ([]*directoryv1.User, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	users := []*directoryv1.User{}
+	for _, user := range w.users {
+		users = append(users, w.publicUser(user))
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].PrimaryEmail < users[j].PrimaryEmail
+	})
+
+	return users, nil
+}
+
+func (w *Workspace) GetUser(ctx context.Context, key string) (*directoryv1.User, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	user := w.findUser(key)
+	if user == nil {
+		return nil, notFound("user %q does not exist", key)
+	}
+
+	return w.publicUser(user), nil
+}
+
+func (w *Workspace) CreateUser(ctx context.Context, user *directoryv1.User) (*directoryv1.User, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if user.PrimaryEmail == "" {
+		return nil, badRequest("primary email is required")
+	}
+
+	if user.Name == nil || user.Name.GivenName == "" || user.Name.FamilyName == "" {
+		return nil, badRequest("given and family name are required")
+	}
+
+	if w.emailTaken(user.PrimaryEmail) {
+		return nil, conflict("entity %q already exists", user.PrimaryEmail)
+	}
+
+	created := &directoryv1.User{}
+	overlay(&directoryv1.User{}, user, created, userReadOnlyFields...)
+
+	if created.OrgUnitPath == "" {
+		created.OrgUnitPath = "/"
+	}
+
+	if w.findOrgUnit(created.OrgUnitPath) == nil {
+		return nil, badRequest("invalid org unit path %q", created.OrgUnitPath)
+	}
+
+	created.Id = w.nextID()
+	created.Etag = w.nextEtag()
+	created.Kind = "admin#directory#user"
+	created.CustomSchemas = user.CustomSchemas
+
+	w.users = append(w.users, created)
+
+	return w.publicUser(created), nil
+}
+
+func (w *Workspace) DeleteUser(ctx context.Context, user *directoryv1.User) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(user.PrimaryEmail)
+	if stored == nil {
+		return notFound("user %q does not exist", user.PrimaryEmail)
+	}
+
+	for i, u := range w.users {
+		if u == stored {
+			w.users = append(w.users[:i], w.users[i+1:]...)
+			break
+		}
+	}
+
+	// like GSuite, remove all memberships and licenses of the deleted user
+	for groupID := range w.members {
+		w.removeMemberByID(groupID, stored.Id)
+	}
+
+	for skuID, emails := range w.licenseAssignments {
+		w.licenseAssignments[skuID] = removeEmail(emails, stored.PrimaryEmail)
+	}
+
+	return nil
+}
+
+func (w *Workspace) UpdateUser(ctx context.Context, oldUser *directoryv1.User, newUser *directoryv1.User) (*directoryv1.User, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(oldUser.PrimaryEmail)
+	if stored == nil {
+		return nil, notFound("user %q does not exist", oldUser.PrimaryEmail)
+	}
+
+	if newUser.PrimaryEmail != "" && !sameEmail(newUser.PrimaryEmail, stored.PrimaryEmail) {
+		if owner := w.findUser(newUser.PrimaryEmail); (owner != nil && owner != stored) || w.findGroup(newUser.PrimaryEmail) != nil {
+			return nil, conflict("entity %q already exists", newUser.PrimaryEmail)
+		}
+	}
+
+	if newUser.OrgUnitPath != "" && w.findOrgUnit(newUser.OrgUnitPath) == nil {
+		return nil, badRequest("invalid org unit path %q", newUser.OrgUnitPath)
+	}
+
+	updated := &directoryv1.User{}
+	overlay(stored, newUser, updated, userReadOnlyFields...)
+
+	// custom schemas are merged, not replaced
+	updated.CustomSchemas = stored.CustomSchemas
+	for name, data := range newUser.CustomSchemas {
+		if updated.CustomSchemas == nil {
+			updated.CustomSchemas = map[string]googleapi.RawMessage{}
+		}
+		updated.CustomSchemas[name] = data
+	}
+
+	// renaming a user keeps the old address as an alias
+	if !sameEmail(updated.PrimaryEmail, stored.PrimaryEmail) {
+		updated.Aliases = append(removeEmail(updated.Aliases, updated.PrimaryEmail), stored.PrimaryEmail)
+		sort.Strings(updated.Aliases)
+
+		for skuID, emails := range w.licenseAssignments {
+			for i, email := range emails {
+				if sameEmail(email, stored.PrimaryEmail) {
+					emails[i] = updated.PrimaryEmail
+				}
+			}
+			w.licenseAssignments[skuID] = emails
+		}
+	}
+
+	updated.Etag = w.nextEtag()
+	*stored = *updated
+
+	return w.publicUser(stored), nil
+}
+
+func (w *Workspace) GetUserAliases(ctx context.Context, user *directoryv1.User) ([]string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(user.PrimaryEmail)
+	if stored == nil {
+		return nil, notFound("user %q does not exist", user.PrimaryEmail)
+	}
+
+	result := append([]string{}, stored.Aliases...)
+	sort.Strings(result)
+
+	return result, nil
+}
+
+func (w *Workspace) CreateUserAlias(ctx context.Context, user *directoryv1.User, alias string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(user.PrimaryEmail)
+	if stored == nil {
+		return notFound("user %q does not exist", user.PrimaryEmail)
+	}
+
+	if w.emailTaken(alias) {
+		return conflict("entity %q already exists", alias)
+	}
+
+	stored.Aliases = append(stored.Aliases, alias)
+	sort.Strings(stored.Aliases)
+	stored.Etag = w.nextEtag()
+
+	return nil
+}
+
+func (w *Workspace) DeleteUserAlias(ctx context.Context, user *directoryv1.User, alias string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(user.PrimaryEmail)
+	if stored == nil {
+		return notFound("user %q does not exist", user.PrimaryEmail)
+	}
+
+	remaining := removeEmail(stored.Aliases, alias)
+	if len(remaining) == len(stored.Aliases) {
+		return notFound("alias %q does not exist", alias)
+	}
+
+	stored.Aliases = remaining
+	stored.Etag = w.nextEtag()
+
+	return nil
+}
+
+func removeEmail(emails []string, email string) []string {
+	result := []string{}
+	for _, e := range emails {
+		if !sameEmail(e, email) {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+)
+
+func (w *Workspace) GetSettings(ctx context.Context, groupId string) (*groupssettingsv1.Groups, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	group := w.findGroup(groupId)
+	if group == nil {
+		return nil, notFound("group %q does not exist", groupId)
+	}
+
+	result := &groupssettingsv1.Groups{}
+	clone(w.settings[group.Id], result)
+
+	return result, nil
+}
+
+func (w *Workspace) UpdateSettings(ctx context.Context, group *directoryv1.Group, settings *groupssettingsv1.Groups) (*groupssettingsv1.Groups, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findGroup(group.Email)
+	if stored == nil {
+		return nil, notFound("group %q does not exist", group.Email)
+	}
+
+	updated := &groupssettingsv1.Groups{}
+	overlay(w.settings[stored.Id], settings, updated, "kind", "email")
+	w.settings[stored.Id] = updated
+
+	result := &groupssettingsv1.Groups{}
+	clone(updated, result)
+
+	return result, nil
+}
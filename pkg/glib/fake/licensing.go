@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func (w *Workspace) GetLicenses() ([]config.License, error) {
+	return w.licenses, nil
+}
+
+func (w *Workspace) GetLicenseByName(name string) *config.License {
+	for k, license := range w.licenses {
+		if license.Name == name {
+			return &w.licenses[k]
+		}
+	}
+
+	return nil
+}
+
+func (w *Workspace) LicenseUsages(ctx context.Context, license config.License) ([]string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]string{}, w.licenseAssignments[license.SkuId]...), nil
+}
+
+func (w *Workspace) AssignLicense(ctx context.Context, user *directoryv1.User, license config.License) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	stored := w.findUser(user.PrimaryEmail)
+	if stored == nil {
+		return notFound("user %q does not exist", user.PrimaryEmail)
+	}
+
+	for _, email := range w.licenseAssignments[license.SkuId] {
+		if sameEmail(email, stored.PrimaryEmail) {
+			return conflict("user %q already has license %q", stored.PrimaryEmail, license.SkuId)
+		}
+	}
+
+	w.licenseAssignments[license.SkuId] = append(w.licenseAssignments[license.SkuId], stored.PrimaryEmail)
+
+	return nil
+}
+
+func (w *Workspace) UnassignLicense(ctx context.Context, user *directoryv1.User, license config.License) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	assignments := w.licenseAssignments[license.SkuId]
+
+	remaining := removeEmail(assignments, user.PrimaryEmail)
+	if len(remaining) == len(assignments) {
+		return notFound("user %q does not have license %q", user.PrimaryEmail, license.SkuId)
+	}
+
+	w.licenseAssignments[license.SkuId] = remaining
+
+	return nil
+}
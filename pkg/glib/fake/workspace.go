@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake contains an in-memory GSuite backend that implements the
+// glib client interfaces, so that synchronizing and exporting can happen
+// without talking to a real Workspace customer.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	datatransferv1 "google.golang.org/api/admin/datatransfer/v1"
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib"
+)
+
+// CustomerID is the ID of every fake customer.
+const CustomerID = "C00fake00"
+
+// Workspace is an in-memory Workspace customer. It implements the
+// DirectoryClient, LicensingClient, GroupsSettingsClient and
+// DataTransferClient interfaces
+// and is safe for concurrent use.
+type Workspace struct {
+	lock sync.Mutex
+
+	organization string
+	licenses     []config.License
+
+	// all objects are kept in insertion order and looked up linearly;
+	// the fake is not meant for large datasets
+	users    []*directoryv1.User
+	groups   []*directoryv1.Group
+	orgUnits []*directoryv1.OrgUnit
+	schemas  []*directoryv1.Schema
+
+	// keyed by group ID
+	members  map[string][]*directoryv1.Member
+	settings map[string]*groupssettingsv1.Groups
+
+	// keyed by SKU ID, values are user primary emails
+	licenseAssignments map[string][]string
+
+	transfers []*datatransferv1.DataTransfer
+
+	lastID  int
+	version int
+}
+
+var (
+	_ glib.DirectoryClient      = &Workspace{}
+	_ glib.LicensingClient      = &Workspace{}
+	_ glib.GroupsSettingsClient = &Workspace{}
+	_ glib.DataTransferClient   = &Workspace{}
+)
+
+// NewWorkspace returns an empty Workspace that knows about the given licenses.
+func NewWorkspace(organization string, licenses []config.License) *Workspace {
+	return &Workspace{
+		organization:       organization,
+		licenses:           licenses,
+		members:            map[string][]*directoryv1.Member{},
+		settings:           map[string]*groupssettingsv1.Groups{},
+		licenseAssignments: map[string][]string{},
+	}
+}
+
+// Organization returns the organization name the fake was created for.
+func (w *Workspace) Organization() string {
+	return w.organization
+}
+
+func (w *Workspace) nextID() string {
+	w.lastID++
+	return fmt.Sprintf("%06d", w.lastID)
+}
+
+func (w *Workspace) nextEtag() string {
+	w.version++
+	return fmt.Sprintf(`"v%d"`, w.version)
+}
+
+// clone creates a deep copy of API objects by round-tripping them through
+// JSON, exactly like they would be sent over the wire.
+func clone(src json.Marshaler, dst interface{}) {
+	encoded, err := src.MarshalJSON()
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode %T: %v", src, err))
+	}
+
+	if err := json.Unmarshal(encoded, dst); err != nil {
+		panic(fmt.Sprintf("failed to decode %T: %v", dst, err))
+	}
+}
+
+// overlay applies all fields that are present in the JSON representation
+// of patch onto dst, skipping the given read-only fields. This mimics the
+// behaviour of the Google APIs, which leave fields untouched when they are
+// omitted in an update.
+func overlay(dst json.Marshaler, patch json.Marshaler, target interface{}, readOnly ...string) {
+	current := map[string]json.RawMessage{}
+	clone(dst, &current)
+
+	changes := map[string]json.RawMessage{}
+	clone(patch, &changes)
+
+	for _, field := range readOnly {
+		delete(changes, field)
+	}
+
+	for field, value := range changes {
+		current[field] = value
+	}
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode %T: %v", dst, err))
+	}
+
+	if err := json.Unmarshal(encoded, target); err != nil {
+		panic(fmt.Sprintf("failed to decode %T: %v", target, err))
+	}
+}
+
+func sameEmail(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+func apiError(code int, format string, args ...interface{}) error {
+	return &googleapi.Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return apiError(http.StatusNotFound, format, args...)
+}
+
+func conflict(format string, args ...interface{}) error {
+	return apiError(http.StatusConflict, format, args...)
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return apiError(http.StatusBadRequest, format, args...)
+}
+
+// emailTaken checks whether the given address is used by any user, user
+// alias or group.
+func (w *Workspace) emailTaken(email string) bool {
+	if w.findUser(email) != nil {
+		return true
+	}
+
+	return w.findGroup(email) != nil
+}
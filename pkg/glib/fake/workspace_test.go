@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func newUser(email string) *directoryv1.User {
+	return &directoryv1.User{
+		PrimaryEmail: email,
+		Name: &directoryv1.UserName{
+			GivenName:  "Jane",
+			FamilyName: "Doe",
+		},
+	}
+}
+
+// errorCode returns the HTTP status of an API error, or 0 for nil.
+func errorCode(t *testing.T, err error) int {
+	t.Helper()
+
+	if err == nil {
+		return 0
+	}
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an API error, but got %v", err)
+	}
+
+	return apiErr.Code
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+
+	testcases := []struct {
+		name     string
+		prepare  func(ws *Workspace)
+		run      func(ws *Workspace) error
+		expected int
+	}{
+		{
+			name: "create a user",
+			run: func(ws *Workspace) error {
+				_, err := ws.CreateUser(ctx, newUser("jane@example.com"))
+				return err
+			},
+		},
+		{
+			name: "reject duplicate emails",
+			prepare: func(ws *Workspace) {
+				ws.CreateUser(ctx, newUser("jane@example.com"))
+			},
+			run: func(ws *Workspace) error {
+				_, err := ws.CreateUser(ctx, newUser("Jane@Example.com"))
+				return err
+			},
+			expected: http.StatusConflict,
+		},
+		{
+			name: "reject emails used as an alias",
+			prepare: func(ws *Workspace) {
+				ws.CreateUser(ctx, newUser("jane@example.com"))
+				ws.CreateUserAlias(ctx, newUser("jane@example.com"), "jd@example.com")
+			},
+			run: func(ws *Workspace) error {
+				_, err := ws.CreateUser(ctx, newUser("jd@example.com"))
+				return err
+			},
+			expected: http.StatusConflict,
+		},
+		{
+			name: "reject unknown org units",
+			run: func(ws *Workspace) error {
+				user := newUser("jane@example.com")
+				user.OrgUnitPath = "/Engineering"
+
+				_, err := ws.CreateUser(ctx, user)
+				return err
+			},
+			expected: http.StatusBadRequest,
+		},
+		{
+			name: "fail to delete unknown users",
+			run: func(ws *Workspace) error {
+				return ws.DeleteUser(ctx, newUser("jane@example.com"))
+			},
+			expected: http.StatusNotFound,
+		},
+		{
+			name: "fail to delete unknown aliases",
+			prepare: func(ws *Workspace) {
+				ws.CreateUser(ctx, newUser("jane@example.com"))
+			},
+			run: func(ws *Workspace) error {
+				return ws.DeleteUserAlias(ctx, newUser("jane@example.com"), "jd@example.com")
+			},
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ws := NewWorkspace("example", config.AllLicenses)
+			if tc.prepare != nil {
+				tc.prepare(ws)
+			}
+
+			if code := errorCode(t, tc.run(ws)); code != tc.expected {
+				t.Fatalf("Expected status %d, but got %d", tc.expected, code)
+			}
+		})
+	}
+}
+
+func TestUpdateUserKeepsOmittedFields(t *testing.T) {
+	ctx := context.Background()
+	ws := NewWorkspace("example", config.AllLicenses)
+
+	user := newUser("jane@example.com")
+	user.Suspended = true
+
+	created, err := ws.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	updated, err := ws.UpdateUser(ctx, created, &directoryv1.User{
+		Name: &directoryv1.UserName{GivenName: "Janet", FamilyName: "Doe"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	if updated.Name.GivenName != "Janet" {
+		t.Errorf("Expected the given name to be updated, but got %q", updated.Name.GivenName)
+	}
+
+	if !updated.Suspended {
+		t.Errorf("Expected the user to stay suspended")
+	}
+}
+
+func TestDeleteUserRemovesMembershipsAndLicenses(t *testing.T) {
+	ctx := context.Background()
+	ws := NewWorkspace("example", config.AllLicenses)
+
+	user, err := ws.CreateUser(ctx, newUser("jane@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	group, err := ws.CreateGroup(ctx, &directoryv1.Group{Name: "Team", Email: "team@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+
+	if err := ws.AddNewMember(ctx, group, &directoryv1.Member{Email: user.PrimaryEmail}); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	license := config.AllLicenses[0]
+	if err := ws.AssignLicense(ctx, user, license); err != nil {
+		t.Fatalf("Failed to assign license: %v", err)
+	}
+
+	if err := ws.DeleteUser(ctx, user); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	members, err := ws.ListMembers(ctx, group)
+	if err != nil {
+		t.Fatalf("Failed to list members: %v", err)
+	}
+
+	if len(members) > 0 {
+		t.Errorf("Expected no members, but got %v", members)
+	}
+
+	usages, err := ws.LicenseUsages(ctx, license)
+	if err != nil {
+		t.Fatalf("Failed to list license usages: %v", err)
+	}
+
+	if len(usages) > 0 {
+		t.Errorf("Expected no license assignments, but got %v", usages)
+	}
+}
+
+func TestOrgUnits(t *testing.T) {
+	ctx := context.Background()
+	ws := NewWorkspace("example", config.AllLicenses)
+
+	if err := ws.CreateOrgUnit(ctx, &directoryv1.OrgUnit{Name: "Platform", ParentOrgUnitPath: "/Engineering"}); errorCode(t, err) != http.StatusBadRequest {
+		t.Fatalf("Expected a missing parent to be rejected, but got %v", err)
+	}
+
+	if err := ws.CreateOrgUnit(ctx, &directoryv1.OrgUnit{Name: "Engineering"}); err != nil {
+		t.Fatalf("Failed to create org unit: %v", err)
+	}
+
+	if err := ws.CreateOrgUnit(ctx, &directoryv1.OrgUnit{Name: "Platform", ParentOrgUnitPath: "/Engineering"}); err != nil {
+		t.Fatalf("Failed to create org unit: %v", err)
+	}
+
+	orgUnits, err := ws.ListOrgUnits(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list org units: %v", err)
+	}
+
+	paths := []string{}
+	for _, orgUnit := range orgUnits {
+		paths = append(paths, orgUnit.OrgUnitPath)
+	}
+
+	expected := []string{"/Engineering", "/Engineering/Platform"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected org units %v, but got %v", expected, paths)
+	}
+
+	if err := ws.DeleteOrgUnit(ctx, orgUnits[0]); errorCode(t, err) != http.StatusBadRequest {
+		t.Fatalf("Expected deleting an org unit with children to fail, but got %v", err)
+	}
+}
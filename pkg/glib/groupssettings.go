@@ -19,44 +19,31 @@ package glib
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"time"
 
-	"golang.org/x/oauth2/google"
 	directoryv1 "google.golang.org/api/admin/directory/v1"
 	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
-	"google.golang.org/api/option"
 )
 
 type GroupsSettingsService struct {
 	*groupssettingsv1.Service
-
-	delay time.Duration
 }
 
 // NewGroupsSettingsService() creates a client for communicating with Google Groupssettings API.
-func NewGroupsSettingsService(ctx context.Context, clientSecretFile string, impersonatedUserEmail string, delay time.Duration) (*GroupsSettingsService, error) {
-	jsonCredentials, err := ioutil.ReadFile(clientSecretFile)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read JSON credentials: %v", err)
-	}
-
-	config, err := google.JWTConfigFromJSON(jsonCredentials, groupssettingsv1.AppsGroupsSettingsScope)
+// If endpoint is not empty, it replaces https://www.googleapis.com/.
+func NewGroupsSettingsService(ctx context.Context, clientSecretFile string, impersonatedUserEmail string, endpoint string, delay time.Duration, budget *RetryBudget) (*GroupsSettingsService, error) {
+	opts, err := clientOptions(ctx, clientSecretFile, impersonatedUserEmail, endpoint, "groups/v1/groups/", delay, budget, groupssettingsv1.AppsGroupsSettingsScope)
 	if err != nil {
-		return nil, fmt.Errorf("unable to process credentials: %v", err)
+		return nil, err
 	}
-	config.Subject = impersonatedUserEmail
-
-	ts := config.TokenSource(ctx)
 
-	srv, err := groupssettingsv1.NewService(ctx, option.WithTokenSource(ts))
+	srv, err := groupssettingsv1.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a new Groupssettings Service: %v", err)
 	}
 
 	groupsService := &GroupsSettingsService{
 		Service: srv,
-		delay:   delay,
 	}
 
 	return groupsService, nil
@@ -71,8 +58,10 @@ func (gs *GroupsSettingsService) GetSettings(ctx context.Context, groupId string
 	return request, nil
 }
 
+// UpdateSettings patches the group's settings; settings that are not set
+// (and not forced to be sent) are left unchanged.
 func (gs *GroupsSettingsService) UpdateSettings(ctx context.Context, group *directoryv1.Group, settings *groupssettingsv1.Groups) (*groupssettingsv1.Groups, error) {
-	updatedSettings, err := gs.Groups.Update(group.Email, settings).Context(ctx).Do()
+	updatedSettings, err := gs.Groups.Patch(group.Email, settings).Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -19,14 +19,11 @@ package glib
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"time"
 
-	"golang.org/x/oauth2/google"
 	directoryv1 "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/licensing/v1"
-	"google.golang.org/api/option"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
 )
@@ -36,25 +33,17 @@ type LicensingService struct {
 
 	organization string
 	licenses     []config.License
-	delay        time.Duration
 }
 
 // NewLicensingService() creates a client for communicating with Google Licensing API.
-func NewLicensingService(ctx context.Context, organization string, clientSecretFile string, impersonatedUserEmail string, delay time.Duration, licenses []config.License) (*LicensingService, error) {
-	jsonCredentials, err := ioutil.ReadFile(clientSecretFile)
+// If endpoint is not empty, it replaces https://licensing.googleapis.com/.
+func NewLicensingService(ctx context.Context, organization string, clientSecretFile string, impersonatedUserEmail string, endpoint string, delay time.Duration, budget *RetryBudget, licenses []config.License) (*LicensingService, error) {
+	opts, err := clientOptions(ctx, clientSecretFile, impersonatedUserEmail, endpoint, "", delay, budget, licensing.AppsLicensingScope)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read JSON credentials: %v", err)
+		return nil, err
 	}
 
-	config, err := google.JWTConfigFromJSON(jsonCredentials, licensing.AppsLicensingScope)
-	if err != nil {
-		return nil, fmt.Errorf("unable to process credentials: %v", err)
-	}
-	config.Subject = impersonatedUserEmail
-
-	ts := config.TokenSource(ctx)
-
-	srv, err := licensing.NewService(ctx, option.WithTokenSource(ts))
+	srv, err := licensing.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a new licensing service: %v", err)
 	}
@@ -63,7 +52,6 @@ func NewLicensingService(ctx context.Context, organization string, clientSecretF
 		Service:      srv,
 		organization: organization,
 		licenses:     licenses,
-		delay:        delay,
 	}
 
 	return licenseService, nil
@@ -84,7 +72,8 @@ func (ls *LicensingService) GetLicenseByName(name string) *config.License {
 	return nil
 }
 
-// LicenseUsages lists all user IDs assigned licenses for a specific product SKU.
+// LicenseUsages lists the primary emails of all users assigned licenses for a
+// specific product SKU.
 func (ls *LicensingService) LicenseUsages(ctx context.Context, license config.License) ([]string, error) {
 	userIDs := []string{}
 	token := ""
@@ -93,7 +82,7 @@ func (ls *LicensingService) LicenseUsages(ctx context.Context, license config.Li
 		// This is the only request in this entire package that actually needs a concrete
 		// organization name instead of "my_customer"; on the other hand, using a concrete
 		// name anywhere else leads to HTTP 401 errors. Go figure.
-		request := ls.LicenseAssignments.ListForProduct(license.ProductId, ls.organization).PageToken(token).Context(ctx)
+		request := ls.LicenseAssignments.ListForProductAndSku(license.ProductId, license.SkuId, ls.organization).PageToken(token).Context(ctx)
 
 		response, err := request.Do()
 		if err != nil {
@@ -132,11 +121,14 @@ func (ls *LicensingService) UnassignLicense(ctx context.Context, user *directory
 }
 
 type LicenseStatus struct {
+	// Assignments maps a user's canonical primary email to the SKU
+	// IDs of all licenses assigned to that user.
 	Assignments map[string][]string
 	Licenses    map[string]config.License
 }
 
-func (ls *LicensingService) GetLicenseStatus(ctx context.Context) (*LicenseStatus, error) {
+// GetLicenseStatus fetches the assignments for all known licenses.
+func GetLicenseStatus(ctx context.Context, ls LicensingClient) (*LicenseStatus, error) {
 	licenses, err := ls.GetLicenses()
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine list of all available licenses: %v", err)
@@ -150,12 +142,17 @@ func (ls *LicensingService) GetLicenseStatus(ctx context.Context) (*LicenseStatu
 	for _, license := range licenses {
 		log.Printf("  %s", license.Name)
 
-		assignments, err := ls.LicenseUsages(ctx, license)
+		// the Licensing API identifies users by their primary email
+		userEmails, err := ls.LicenseUsages(ctx, license)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch license usages: %v", err)
 		}
 
-		status.Assignments[license.SkuId] = assignments
+		for _, email := range userEmails {
+			email = config.CanonicalEmail(email)
+			status.Assignments[email] = append(status.Assignments[email], license.SkuId)
+		}
+
 		status.Licenses[license.SkuId] = license
 	}
 
@@ -165,7 +162,7 @@ func (ls *LicensingService) GetLicenseStatus(ctx context.Context) (*LicenseStatu
 func (ls *LicenseStatus) GetLicensesForUser(user *directoryv1.User) []config.License {
 	result := []config.License{}
 
-	for _, skuId := range ls.Assignments[user.Id] {
+	for _, skuId := range ls.Assignments[config.CanonicalEmail(user.PrimaryEmail)] {
 		result = append(result, ls.Licenses[skuId])
 	}
 
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"context"
+	"testing"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// staticLicensing reports fixed license usages, keyed by SKU ID.
+type staticLicensing struct {
+	licenses []config.License
+	usages   map[string][]string
+}
+
+func (s *staticLicensing) GetLicenses() ([]config.License, error) {
+	return s.licenses, nil
+}
+
+func (s *staticLicensing) GetLicenseByName(name string) *config.License {
+	return nil
+}
+
+func (s *staticLicensing) LicenseUsages(ctx context.Context, license config.License) ([]string, error) {
+	return s.usages[license.SkuId], nil
+}
+
+func (s *staticLicensing) AssignLicense(ctx context.Context, user *directoryv1.User, license config.License) error {
+	return nil
+}
+
+func (s *staticLicensing) UnassignLicense(ctx context.Context, user *directoryv1.User, license config.License) error {
+	return nil
+}
+
+func TestGetLicensesForUser(t *testing.T) {
+	starter := config.License{Name: "Starter", ProductId: "product", SkuId: "starter"}
+	standard := config.License{Name: "Standard", ProductId: "product", SkuId: "standard"}
+
+	status, err := GetLicenseStatus(context.Background(), &staticLicensing{
+		licenses: []config.License{starter, standard},
+		usages: map[string][]string{
+			"starter":  {"Jane@Example.com", "bob@example.com"},
+			"standard": {"jane@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to get license status: %v", err)
+	}
+
+	testcases := []struct {
+		email    string
+		expected []string
+	}{
+		{
+			email:    "jane@example.com",
+			expected: []string{"Starter", "Standard"},
+		},
+		{
+			email:    "Bob@Example.com",
+			expected: []string{"Starter"},
+		},
+		{
+			email:    "nobody@example.com",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.email, func(t *testing.T) {
+			names := []string{}
+			for _, license := range status.GetLicensesForUser(&directoryv1.User{PrimaryEmail: tc.email}) {
+				names = append(names, license.Name)
+			}
+
+			if len(names) != len(tc.expected) {
+				t.Fatalf("Expected licenses %v, but got %v", tc.expected, names)
+			}
+
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Fatalf("Expected licenses %v, but got %v", tc.expected, names)
+				}
+			}
+		})
+	}
+}
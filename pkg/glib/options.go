@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// clientOptions returns the options to create an API client with. If an
+// endpoint is given, it is used instead of the googleapis.com default and
+// must point to the root of a server that serves the same URL paths as
+// googleapis.com does; apiPath is appended to it. When talking to a custom
+// endpoint, the credentials are optional.
+// All requests are paced to one per delay and retried according to the
+// retry budget.
+func clientOptions(ctx context.Context, clientSecretFile string, impersonatedUserEmail string, endpoint string, apiPath string, delay time.Duration, budget *RetryBudget, scopes ...string) ([]option.ClientOption, error) {
+	opts := []option.ClientOption{}
+	transport := http.DefaultTransport
+
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(strings.TrimSuffix(endpoint, "/")+"/"+apiPath))
+	}
+
+	if endpoint == "" || clientSecretFile != "" {
+		jsonCredentials, err := ioutil.ReadFile(clientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read JSON credentials: %v", err)
+		}
+
+		config, err := google.JWTConfigFromJSON(jsonCredentials, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to process credentials: %v", err)
+		}
+		config.Subject = impersonatedUserEmail
+
+		transport = &oauth2.Transport{
+			Source: config.TokenSource(ctx),
+			Base:   transport,
+		}
+	}
+
+	client := &http.Client{
+		Transport: newRetryTransport(transport, delay, budget),
+	}
+
+	return append(opts, option.WithHTTPClient(client)), nil
+}
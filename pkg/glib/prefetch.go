@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	groupssettingsv1 "google.golang.org/api/groupssettings/v1"
+)
+
+// GroupDetails are the members, aliases and settings of a group, which
+// have to be fetched individually for every group.
+type GroupDetails struct {
+	Members  []*directoryv1.Member
+	Aliases  []string
+	Settings *groupssettingsv1.Groups
+}
+
+// FetchGroupDetails fetches the members, aliases and settings of all given groups,
+// using up to concurrency parallel workers. The result is keyed by the
+// group email. Requests are still subject to each client's rate limit.
+func FetchGroupDetails(
+	ctx context.Context,
+	directorySrv DirectoryClient,
+	groupsSettingsSrv GroupsSettingsClient,
+	groups []*directoryv1.Group,
+	concurrency int,
+) (map[string]*GroupDetails, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	result := map[string]*GroupDetails{}
+	lock := sync.Mutex{}
+
+	wg, ctx := errgroup.WithContext(ctx)
+	wg.SetLimit(concurrency)
+
+	for _, group := range groups {
+		group := group
+
+		wg.Go(func() error {
+			members, err := directorySrv.ListMembers(ctx, group)
+			if err != nil {
+				return fmt.Errorf("failed to fetch members of %s: %v", group.Email, err)
+			}
+
+			aliases, err := directorySrv.GetGroupAliases(ctx, group)
+			if err != nil {
+				return fmt.Errorf("failed to fetch aliases of %s: %v", group.Email, err)
+			}
+
+			settings, err := groupsSettingsSrv.GetSettings(ctx, group.Email)
+			if err != nil {
+				return fmt.Errorf("failed to fetch settings of %s: %v", group.Email, err)
+			}
+
+			lock.Lock()
+			result[group.Email] = &GroupDetails{
+				Members:  members,
+				Aliases:  aliases,
+				Settings: settings,
+			}
+			lock.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+// countingDirectory records how many members requests run in parallel.
+type countingDirectory struct {
+	*fake.Workspace
+
+	lock    sync.Mutex
+	running int
+	peak    int
+}
+
+func (d *countingDirectory) ListMembers(ctx context.Context, group *directoryv1.Group) ([]*directoryv1.Member, error) {
+	d.lock.Lock()
+	d.running++
+	if d.running > d.peak {
+		d.peak = d.running
+	}
+	d.lock.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	d.lock.Lock()
+	d.running--
+	d.lock.Unlock()
+
+	return d.Workspace.ListMembers(ctx, group)
+}
+
+func TestFetchGroupDetails(t *testing.T) {
+	const concurrency = 3
+
+	ctx := context.Background()
+	ws := fake.NewWorkspace("example", config.AllLicenses)
+
+	groups := []*directoryv1.Group{}
+	for i := 0; i < 10; i++ {
+		group, err := ws.CreateGroup(ctx, &directoryv1.Group{
+			Name:  fmt.Sprintf("Group %d", i),
+			Email: fmt.Sprintf("group%d@example.com", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create group: %v", err)
+		}
+
+		if err := ws.AddNewMember(ctx, group, &directoryv1.Member{Email: fmt.Sprintf("member%d@example.com", i)}); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	directory := &countingDirectory{Workspace: ws}
+
+	details, err := glib.FetchGroupDetails(ctx, directory, ws, groups, concurrency)
+	if err != nil {
+		t.Fatalf("Failed to fetch group details: %v", err)
+	}
+
+	if directory.peak > concurrency {
+		t.Errorf("Expected at most %d parallel requests, but got %d", concurrency, directory.peak)
+	}
+
+	for i, group := range groups {
+		detail, ok := details[group.Email]
+		if !ok {
+			t.Fatalf("Expected details for %s", group.Email)
+		}
+
+		expected := fmt.Sprintf("member%d@example.com", i)
+		if len(detail.Members) != 1 || detail.Members[0].Email != expected {
+			t.Errorf("Expected %s to have member %s, but got %v", group.Email, expected, detail.Members)
+		}
+
+		if detail.Settings == nil {
+			t.Errorf("Expected settings for %s", group.Email)
+		}
+	}
+
+	// errors of a single group fail the whole fetch
+	groups = append(groups, &directoryv1.Group{Email: "missing@example.com"})
+
+	if _, err := glib.FetchGroupDetails(ctx, directory, ws, groups, concurrency); err == nil {
+		t.Fatalf("Expected fetching a missing group to fail")
+	}
+}
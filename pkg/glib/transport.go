@@ -0,0 +1,285 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// maxAttempts is the number of times a single request is tried before
+// its last error is returned to the caller.
+const maxAttempts = 6
+
+// initialBackoff and maxBackoff bound the exponential delay between
+// retries.
+var (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 32 * time.Second
+)
+
+// rateLimitReasons are the reasons of 403 errors that indicate that a
+// quota was hit, rather than that the request was actually forbidden.
+var rateLimitReasons = sets.NewString(
+	"rateLimitExceeded",
+	"userRateLimitExceeded",
+	"quotaExceeded",
+)
+
+// idempotentMethods are the HTTP methods whose requests are retried after
+// server and transport errors, when the server might have processed them
+// already.
+var idempotentMethods = sets.NewString(
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+)
+
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted, giving up")
+
+// RetryBudget limits the total number of retries across all API clients,
+// so that GMan gives up instead of retrying for hours when an API keeps
+// failing. A nil budget allows unlimited retries.
+type RetryBudget struct {
+	lock      sync.Mutex
+	remaining int
+}
+
+func NewRetryBudget(retries int) *RetryBudget {
+	return &RetryBudget{remaining: retries}
+}
+
+// take consumes one retry and returns false if none were left.
+func (b *RetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+
+	b.remaining--
+
+	return true
+}
+
+// retryTransport paces requests using a token bucket and retries requests
+// that failed because of rate limits or, for idempotent requests, because
+// of transient server or transport errors, using exponential backoff with
+// jitter.
+type retryTransport struct {
+	base    http.RoundTripper
+	limiter *rate.Limiter
+	budget  *RetryBudget
+}
+
+// newRetryTransport returns a transport that sends at most one request
+// per delay to the base transport; a delay of 0 disables pacing.
+func newRetryTransport(base http.RoundTripper, delay time.Duration, budget *RetryBudget) *retryTransport {
+	limit := rate.Inf
+	if delay > 0 {
+		limit = rate.Every(delay)
+	}
+
+	return &retryTransport{
+		base:    base,
+		limiter: rate.NewLimiter(limit, 1),
+		budget:  budget,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		// requests whose body cannot be sent again cannot be retried
+		rewindable := req.Body == nil || req.GetBody != nil
+
+		var failure string
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			// transport errors like connection resets are only retried
+			// for requests that can safely be sent twice
+			if attempt >= maxAttempts || !rewindable || !idempotentMethods.Has(req.Method) || ctx.Err() != nil {
+				return nil, err
+			}
+
+			failure = err.Error()
+		} else {
+			if attempt >= maxAttempts || !rewindable || !shouldRetry(req, resp) {
+				return resp, nil
+			}
+
+			// drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			failure = resp.Status
+		}
+
+		if !t.budget.take() {
+			return nil, fmt.Errorf("%w (last error: %s)", ErrRetryBudgetExhausted, failure)
+		}
+
+		var delay time.Duration
+		if resp != nil {
+			delay = retryAfter(resp)
+		}
+		if delay == 0 {
+			delay = backoff(attempt)
+		}
+
+		log.Printf("  ⚠ %s %s failed with %s, retrying in %v…", req.Method, req.URL.Path, failure, delay.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// rewindRequest returns the request to send for the given attempt; for
+// retries, this is a copy with a fresh body.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %v", err)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+
+	return clone, nil
+}
+
+// shouldRetry returns true if the request can be sent again. Rate-limited
+// requests were rejected before being processed and are always retried;
+// server errors only for idempotent requests, as e.g. a group might have
+// been created before the server failed.
+func shouldRetry(req *http.Request, resp *http.Response) bool {
+	idempotent := idempotentMethods.Has(req.Method)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return idempotent
+	case resp.StatusCode == http.StatusForbidden:
+		reasons := errorReasons(resp)
+
+		// backend errors are server errors reported as 403
+		return rateLimitReasons.HasAny(reasons...) || (idempotent && sets.NewString(reasons...).Has("backendError"))
+	default:
+		return false
+	}
+}
+
+// errorReasons returns the reasons from a Google API error response,
+// leaving the response body intact.
+func errorReasons(resp *http.Response) []string {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err != nil {
+		return nil
+	}
+
+	apiErr := struct {
+		Error struct {
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}{}
+
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return nil
+	}
+
+	reasons := []string{}
+	for _, e := range apiErr.Error.Errors {
+		reasons = append(reasons, e.Reason)
+	}
+
+	return reasons
+}
+
+// retryAfter returns the delay requested by the server via the
+// Retry-After header, or 0 if there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// backoff returns the exponential delay before the given retry, with
+// a random jitter of up to 50% to spread out concurrent retries.
+func backoff(attempt int) time.Duration {
+	delay := initialBackoff << (attempt - 1)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
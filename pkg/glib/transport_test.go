@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package glib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// retries are logged
+	log.SetOutput(io.Discard)
+
+	// keep retries fast
+	initialBackoff = time.Millisecond
+	maxBackoff = 4 * time.Millisecond
+
+	os.Exit(m.Run())
+}
+
+// scriptedResponse is a response with the given status and, for errors,
+// a Google API error body with the given reason. If err is set, the
+// request fails without a response instead.
+type scriptedResponse struct {
+	status int
+	reason string
+	err    error
+}
+
+// scriptedTransport returns its responses in order and records the
+// bodies of all requests it receives.
+type scriptedTransport struct {
+	responses []scriptedResponse
+	bodies    []string
+}
+
+func (t *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(content)
+	}
+
+	response := t.responses[len(t.bodies)]
+	t.bodies = append(t.bodies, body)
+
+	if response.err != nil {
+		return nil, response.err
+	}
+
+	content := "{}"
+	if response.status >= 400 {
+		content = fmt.Sprintf(`{"error":{"code":%d,"errors":[{"reason":%q}]}}`, response.status, response.reason)
+	}
+
+	return &http.Response{
+		StatusCode: response.status,
+		Status:     fmt.Sprintf("%d %s", response.status, http.StatusText(response.status)),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(content)),
+		Request:    req,
+	}, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	ok := scriptedResponse{status: http.StatusOK}
+	serverError := scriptedResponse{status: http.StatusServiceUnavailable}
+	reset := scriptedResponse{err: errors.New("connection reset by peer")}
+
+	testcases := []struct {
+		name      string
+		method    string
+		responses []scriptedResponse
+		budget    *RetryBudget
+		status    int
+		requests  int
+	}{
+		{
+			name:      "retry rate-limited requests",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{{status: http.StatusTooManyRequests}, ok},
+			status:    http.StatusOK,
+			requests:  2,
+		},
+		{
+			name:      "retry server errors",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{serverError, serverError, ok},
+			status:    http.StatusOK,
+			requests:  3,
+		},
+		{
+			name:      "retry exceeded quotas",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{{status: http.StatusForbidden, reason: "rateLimitExceeded"}, ok},
+			status:    http.StatusOK,
+			requests:  2,
+		},
+		{
+			name:      "do not retry forbidden requests",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{{status: http.StatusForbidden, reason: "forbidden"}},
+			status:    http.StatusForbidden,
+			requests:  1,
+		},
+		{
+			name:      "do not retry client errors",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{{status: http.StatusNotFound, reason: "notFound"}},
+			status:    http.StatusNotFound,
+			requests:  1,
+		},
+		{
+			name:      "give up after the maximum number of attempts",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{serverError, serverError, serverError, serverError, serverError, serverError},
+			status:    http.StatusServiceUnavailable,
+			requests:  maxAttempts,
+		},
+		{
+			name:      "give up when the budget is exhausted",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{serverError, serverError, ok},
+			budget:    NewRetryBudget(1),
+			requests:  2,
+		},
+		{
+			name:      "resend request bodies",
+			method:    http.MethodPut,
+			responses: []scriptedResponse{serverError, ok},
+			status:    http.StatusOK,
+			requests:  2,
+		},
+		{
+			name:      "retry rate-limited non-idempotent requests",
+			method:    http.MethodPost,
+			responses: []scriptedResponse{{status: http.StatusTooManyRequests}, {status: http.StatusForbidden, reason: "userRateLimitExceeded"}, ok},
+			status:    http.StatusOK,
+			requests:  3,
+		},
+		{
+			name:      "do not retry non-idempotent requests after server errors",
+			method:    http.MethodPost,
+			responses: []scriptedResponse{serverError, ok},
+			status:    http.StatusServiceUnavailable,
+			requests:  1,
+		},
+		{
+			name:      "retry idempotent requests after backend errors",
+			method:    http.MethodDelete,
+			responses: []scriptedResponse{{status: http.StatusForbidden, reason: "backendError"}, ok},
+			status:    http.StatusOK,
+			requests:  2,
+		},
+		{
+			name:      "do not retry non-idempotent requests after backend errors",
+			method:    http.MethodPost,
+			responses: []scriptedResponse{{status: http.StatusForbidden, reason: "backendError"}, ok},
+			status:    http.StatusForbidden,
+			requests:  1,
+		},
+		{
+			name:      "retry idempotent requests after transport errors",
+			method:    http.MethodGet,
+			responses: []scriptedResponse{reset, reset, ok},
+			status:    http.StatusOK,
+			requests:  3,
+		},
+		{
+			name:      "do not retry other requests after transport errors",
+			method:    http.MethodPost,
+			responses: []scriptedResponse{reset, ok},
+			requests:  1,
+		},
+		{
+			name:      "transport error retries count against the budget",
+			method:    http.MethodDelete,
+			responses: []scriptedResponse{reset, reset, ok},
+			budget:    NewRetryBudget(1),
+			requests:  2,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			base := &scriptedTransport{responses: tc.responses}
+			transport := newRetryTransport(base, 0, tc.budget)
+
+			req, err := http.NewRequest(tc.method, "http://example.com/", strings.NewReader(`{"name":"Team"}`))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+
+			switch {
+			case tc.status == 0 && err == nil:
+				t.Fatalf("Expected the request to fail, but got %s", resp.Status)
+			case tc.status != 0 && err != nil:
+				t.Fatalf("Expected the request to succeed, but got %v", err)
+			case tc.status != 0 && resp.StatusCode != tc.status:
+				t.Fatalf("Expected status %d, but got %s", tc.status, resp.Status)
+			}
+
+			if len(base.bodies) != tc.requests {
+				t.Fatalf("Expected %d requests, but %d were sent", tc.requests, len(base.bodies))
+			}
+
+			for _, body := range base.bodies {
+				if body != `{"name":"Team"}` {
+					t.Fatalf("Expected every request to send the body, but got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportPacing(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	ok := scriptedResponse{status: http.StatusOK}
+	base := &scriptedTransport{responses: []scriptedResponse{ok, ok, ok}}
+	transport := newRetryTransport(base, delay, nil)
+
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+
+	// the first request is sent immediately
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Fatalf("Expected 3 requests to take at least %v, but they took %v", 2*delay, elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	testcases := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{header: "", expected: 0},
+		{header: "3", expected: 3 * time.Second},
+		{header: "soon", expected: 0},
+		{header: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), expected: 0},
+	}
+
+	for _, tc := range testcases {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+
+		if delay := retryAfter(resp); delay != tc.expected {
+			t.Errorf("Expected Retry-After %q to give %v, but got %v", tc.header, tc.expected, delay)
+		}
+	}
+}
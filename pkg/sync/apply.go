@@ -0,0 +1,402 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib"
+)
+
+// ApplyPlan executes all actions of the plan in order, stopping at the
+// first error.
+func ApplyPlan(
+	ctx context.Context,
+	plan *Plan,
+	directorySrv glib.DirectoryClient,
+	licensingSrv glib.LicensingClient,
+	groupsSettingsSrv glib.GroupsSettingsClient,
+	dataTransferSrv glib.DataTransferClient,
+) error {
+	for _, action := range plan.Actions {
+		var err error
+
+		switch action.Resource {
+		case OrgUnitResource:
+			err = applyOrgUnitAction(ctx, directorySrv, action)
+		case SchemaResource:
+			err = applySchemaAction(ctx, directorySrv, action)
+		case UserResource:
+			err = applyUserAction(ctx, directorySrv, dataTransferSrv, action)
+		case AliasResource:
+			err = applyAliasAction(ctx, directorySrv, action)
+		case GroupAliasResource:
+			err = applyGroupAliasAction(ctx, directorySrv, action)
+		case LicenseResource:
+			err = applyLicenseAction(ctx, licensingSrv, action)
+		case GroupResource:
+			err = applyGroupAction(ctx, directorySrv, groupsSettingsSrv, action)
+		case MemberResource:
+			err = applyMemberAction(ctx, directorySrv, action)
+		default:
+			err = fmt.Errorf("unknown resource %q", action.Resource)
+		}
+
+		// deleting a resource can implicitly delete others (e.g. a user's
+		// group memberships), so treat already deleted resources as success
+		if action.Operation == DeleteOperation && isNotFound(err) {
+			err = nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %v", action.Operation, action.Resource, action.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	apiErr := &googleapi.Error{}
+
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func applyOrgUnitAction(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	liveOrgUnit := &directoryv1.OrgUnit{OrgUnitId: action.ID}
+
+	if action.Operation == DeleteOperation {
+		return directorySrv.DeleteOrgUnit(ctx, liveOrgUnit)
+	}
+
+	after := action.After
+	if after == nil || after.OrgUnit == nil {
+		return fmt.Errorf("no org unit given")
+	}
+
+	orgUnit := config.ToGSuiteOrgUnit(after.OrgUnit)
+
+	if action.Operation == CreateOperation {
+		return directorySrv.CreateOrgUnit(ctx, orgUnit)
+	}
+
+	return directorySrv.UpdateOrgUnit(ctx, liveOrgUnit, orgUnit)
+}
+
+func applySchemaAction(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	after := action.After
+	if after == nil || after.Schema == nil {
+		return fmt.Errorf("no schema given")
+	}
+
+	var err error
+	switch action.Operation {
+	case CreateOperation:
+		_, err = directorySrv.CreateSchema(ctx, after.Schema)
+	case UpdateOperation:
+		_, err = directorySrv.UpdateSchema(ctx, &directoryv1.Schema{SchemaId: action.ID}, after.Schema)
+	default:
+		err = fmt.Errorf("schemas cannot be deleted")
+	}
+
+	return err
+}
+
+func applyUserAction(ctx context.Context, directorySrv glib.DirectoryClient, dataTransferSrv glib.DataTransferClient, action Action) error {
+	liveUser := &directoryv1.User{PrimaryEmail: action.Name}
+	if action.Operation == RenameOperation {
+		liveUser.PrimaryEmail = action.OldName
+	}
+
+	switch action.Operation {
+	case DeleteOperation:
+		if action.TransferTo != "" {
+			if err := transferUserData(ctx, dataTransferSrv, action); err != nil {
+				return fmt.Errorf("failed to transfer data to %s: %v", action.TransferTo, err)
+			}
+		}
+
+		return directorySrv.DeleteUser(ctx, liveUser)
+	case SuspendOperation, MoveOperation:
+		return applyUserRemoval(ctx, directorySrv, action)
+	case UnsuspendOperation:
+		return applyUserUnsuspension(ctx, directorySrv, action)
+	}
+
+	after := action.After
+	if after == nil || after.User == nil {
+		return fmt.Errorf("no user given")
+	}
+
+	// the plan only contains a password hash if insecure passwords
+	// were enabled when it was created
+	apiUser := config.ToGSuiteUser(after.User, true)
+
+	var err error
+	if action.Operation == CreateOperation {
+		_, err = directorySrv.CreateUser(ctx, apiUser)
+	} else {
+		_, err = directorySrv.UpdateUser(ctx, liveUser, apiUser)
+	}
+
+	return err
+}
+
+// transferPollInterval is the time between checks whether a data transfer
+// has completed.
+var transferPollInterval = 10 * time.Second
+
+// transferUserData transfers a user's data to the action's recipient and
+// waits until the transfer has completed.
+func transferUserData(ctx context.Context, dataTransferSrv glib.DataTransferClient, action Action) error {
+	transfer, err := dataTransferSrv.TransferUserData(ctx, action.ID, action.TransferToID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		switch transfer.OverallTransferStatusCode {
+		case glib.TransferCompleted:
+			return nil
+		case glib.TransferFailed:
+			return fmt.Errorf("transfer %s failed", transfer.Id)
+		}
+
+		log.Printf("  ☞ Waiting for the data transfer of %s to complete…", action.Name)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(transferPollInterval):
+		}
+
+		transfer, err = dataTransferSrv.GetTransfer(ctx, transfer.Id)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// applyUserRemoval suspends or moves a user, leaving all other attributes
+// untouched.
+func applyUserRemoval(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	if action.Before == nil || action.Before.User == nil {
+		return fmt.Errorf("no user given")
+	}
+
+	// UpdateUser always sends the recovery fields, so they have to be kept
+	apiUser := &directoryv1.User{
+		RecoveryEmail: action.Before.User.RecoveryEmail,
+		RecoveryPhone: action.Before.User.RecoveryPhone,
+	}
+
+	if action.Operation == MoveOperation {
+		if action.After == nil || action.After.User == nil {
+			return fmt.Errorf("no target org unit given")
+		}
+
+		apiUser.OrgUnitPath = action.After.User.OrgUnitPath
+	} else {
+		apiUser.Suspended = true
+
+		if action.DeleteAfter != "" {
+			liveUser, err := directorySrv.GetUser(ctx, action.Name)
+			if err != nil {
+				return err
+			}
+
+			customSchemas, err := userSchemaWithDeleteAfter(liveUser, action.DeleteAfter)
+			if err != nil {
+				return err
+			}
+
+			apiUser.CustomSchemas = customSchemas
+		}
+	}
+
+	_, err := directorySrv.UpdateUser(ctx, &directoryv1.User{PrimaryEmail: action.Name}, apiUser)
+
+	return err
+}
+
+// applyUserUnsuspension reactivates a user and clears their deletion date,
+// leaving all other attributes untouched.
+func applyUserUnsuspension(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	liveUser, err := directorySrv.GetUser(ctx, action.Name)
+	if err != nil {
+		return err
+	}
+
+	customSchemas, err := userSchemaWithDeleteAfter(liveUser, "")
+	if err != nil {
+		return err
+	}
+
+	// UpdateUser always sends the recovery fields, so they have to be kept
+	apiUser := &directoryv1.User{
+		RecoveryEmail:   liveUser.RecoveryEmail,
+		RecoveryPhone:   liveUser.RecoveryPhone,
+		Suspended:       false,
+		CustomSchemas:   customSchemas,
+		ForceSendFields: []string{"Suspended"},
+	}
+
+	_, err = directorySrv.UpdateUser(ctx, &directoryv1.User{PrimaryEmail: action.Name}, apiUser)
+
+	return err
+}
+
+// userSchemaWithDeleteAfter returns the user's live custom schema with the
+// given deletion date. Updates replace the schema as a whole, so all other
+// fields, like the password hash, have to be kept.
+func userSchemaWithDeleteAfter(liveUser *directoryv1.User, deleteAfter string) (map[string]googleapi.RawMessage, error) {
+	schema := config.GetUserSchema(liveUser)
+	if schema == nil {
+		schema = &config.CustomSchema{}
+	}
+
+	schema.DeleteAfter = deleteAfter
+
+	encoded, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode custom schema: %v", err)
+	}
+
+	return map[string]googleapi.RawMessage{
+		config.SchemaName: encoded,
+	}, nil
+}
+
+func applyAliasAction(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	user := &directoryv1.User{PrimaryEmail: action.Parent}
+
+	switch action.Operation {
+	case CreateOperation:
+		return directorySrv.CreateUserAlias(ctx, user, action.Name)
+	case DeleteOperation:
+		return directorySrv.DeleteUserAlias(ctx, user, action.Name)
+	default:
+		return fmt.Errorf("aliases cannot be updated")
+	}
+}
+
+func applyGroupAliasAction(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	group := &directoryv1.Group{Email: action.Parent}
+
+	switch action.Operation {
+	case CreateOperation:
+		return directorySrv.CreateGroupAlias(ctx, group, action.Name)
+	case DeleteOperation:
+		return directorySrv.DeleteGroupAlias(ctx, group, action.Name)
+	default:
+		return fmt.Errorf("aliases cannot be updated")
+	}
+}
+
+func applyLicenseAction(ctx context.Context, licensingSrv glib.LicensingClient, action Action) error {
+	license := licensingSrv.GetLicenseByName(action.Name)
+	if license == nil {
+		return fmt.Errorf("unknown license")
+	}
+
+	user := &directoryv1.User{PrimaryEmail: action.Parent}
+
+	switch action.Operation {
+	case CreateOperation:
+		return licensingSrv.AssignLicense(ctx, user, *license)
+	case DeleteOperation:
+		return licensingSrv.UnassignLicense(ctx, user, *license)
+	default:
+		return fmt.Errorf("licenses cannot be updated")
+	}
+}
+
+func applyGroupAction(ctx context.Context, directorySrv glib.DirectoryClient, groupsSettingsSrv glib.GroupsSettingsClient, action Action) error {
+	liveGroup := &directoryv1.Group{Id: action.ID, Email: action.Name}
+	if action.Operation == RenameOperation {
+		liveGroup.Email = action.OldName
+	}
+
+	if action.Operation == DeleteOperation {
+		return directorySrv.DeleteGroup(ctx, liveGroup)
+	}
+
+	after := action.After
+	if after == nil || after.Group == nil {
+		return fmt.Errorf("no group given")
+	}
+
+	group, settings := config.ToGSuiteGroup(after.Group)
+
+	var err error
+	if action.Operation == CreateOperation {
+		group, err = directorySrv.CreateGroup(ctx, group)
+	} else {
+		group, err = directorySrv.UpdateGroup(ctx, liveGroup, group)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := groupsSettingsSrv.UpdateSettings(ctx, group, settings); err != nil {
+		return fmt.Errorf("failed to update group settings: %v", err)
+	}
+
+	return nil
+}
+
+func applyMemberAction(ctx context.Context, directorySrv glib.DirectoryClient, action Action) error {
+	group := &directoryv1.Group{Id: action.ParentID, Email: action.Parent}
+	liveMember := &directoryv1.Member{Id: action.ID, Email: action.Name}
+
+	if action.Operation == DeleteOperation {
+		return directorySrv.RemoveMember(ctx, group, liveMember)
+	}
+
+	after := action.After
+	if after == nil || after.Member == nil {
+		return fmt.Errorf("no member given")
+	}
+
+	if action.Operation == CreateOperation {
+		member := config.ToGSuiteGroupMember(after.Member, nil)
+
+		// CUSTOMER members have no email and are identified by the customer ID
+		if member.Type == config.MemberTypeCustomer && member.Id == "" {
+			customerID, err := directorySrv.CustomerID(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to determine customer ID: %v", err)
+			}
+
+			member.Id = customerID
+		}
+
+		return directorySrv.AddNewMember(ctx, group, member)
+	}
+
+	return directorySrv.UpdateMembership(ctx, group, config.ToGSuiteGroupMember(after.Member, liveMember))
+}
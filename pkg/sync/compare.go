@@ -20,43 +20,66 @@ import (
 	"reflect"
 
 	directoryv1 "google.golang.org/api/admin/directory/v1"
-	"google.golang.org/api/groupssettings/v1"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
 )
 
-func orgUnitUpToDate(configured config.OrgUnit, live *directoryv1.OrgUnit) bool {
-	converted := config.ToConfigOrgUnit(live)
+func orgUnitUpToDate(configured config.OrgUnit, live config.OrgUnit) bool {
+	return reflect.DeepEqual(orgUnitAttributes(configured), orgUnitAttributes(live))
+}
+
+// orgUnitAttributes returns a copy of the org unit without its previous
+// paths, which only exist in the configuration.
+func orgUnitAttributes(orgUnit config.OrgUnit) config.OrgUnit {
+	orgUnit.PreviousPaths = nil
 
-	return reflect.DeepEqual(configured, converted)
+	return orgUnit
 }
 
-func userUpToDate(configured config.User, live *directoryv1.User, liveLicenses []config.License, liveAliases []string) bool {
-	converted, err := config.ToConfigUser(live, liveLicenses)
-	if err != nil {
+func schemaUpToDate(configured *directoryv1.Schema, live *directoryv1.Schema) bool {
+	if configured.DisplayName != live.DisplayName || len(configured.Fields) != len(live.Fields) {
 		return false
 	}
 
-	if converted.Aliases == nil {
-		converted.Aliases = []string{}
-	}
+	for i, field := range configured.Fields {
+		liveField := live.Fields[i]
 
-	if configured.Aliases == nil {
-		configured.Aliases = []string{}
+		if field.FieldName != liveField.FieldName ||
+			field.FieldType != liveField.FieldType ||
+			field.ReadAccessType != liveField.ReadAccessType ||
+			!reflect.DeepEqual(field.Indexed, liveField.Indexed) {
+			return false
+		}
 	}
 
-	// password changes are handled by passwordUpToDate()
-	converted.Password = configured.Password
+	return true
+}
 
-	return reflect.DeepEqual(configured, converted)
+// userUpToDate compares the user's attributes; aliases, licenses and
+// passwords are handled separately.
+func userUpToDate(configured config.User, live config.User) bool {
+	return reflect.DeepEqual(userAttributes(configured), userAttributes(live))
+}
+
+// userAttributes returns a copy of the user without any aliases,
+// previous emails, licenses and password.
+func userAttributes(user config.User) config.User {
+	user.Aliases = nil
+	user.PreviousEmails = nil
+	user.Licenses = nil
+	user.Password = ""
+	user.PasswordHash = ""
+	user.HashFunction = ""
+
+	return user
 }
 
 // passwordUpToDate checks if the live account's last password set
 // by GMan was what is configured in YAML. This is meant as a mechanism to
 // mass-reset accounts to a common, public password, e.g. for testing
-// or training accounts. For this reason GMan stores an unsalted hash
-// of the password as a custom field, with the hash purely being for
-// obfuscating it a bit.
+// or training accounts. For this reason GMan stores the password's
+// crypt hash (or, for passwords set by older versions, a shortened
+// unsalted hash) as a custom field.
 func passwordUpToDate(configured config.User, live *directoryv1.User) bool {
 	// no password configured, so we do not care at all about the
 	// state in GSuite; this is the norm for accounts managed by us
@@ -64,31 +87,41 @@ func passwordUpToDate(configured config.User, live *directoryv1.User) bool {
 		return true
 	}
 
-	configuredHash := config.HashPassword(configured.Password)
 	liveSchema := config.GetUserSchema(live)
 
-	return liveSchema != nil && liveSchema.PasswordHash == configuredHash
+	return liveSchema != nil && config.PasswordMatches(configured.Password, liveSchema.PasswordHash)
 }
 
-func groupUpToDate(configured config.Group, live *directoryv1.Group, liveMembers []*directoryv1.Member, settings *groupssettings.Groups) bool {
-	converted, err := config.ToConfigGroup(live, settings, liveMembers)
-	if err != nil {
-		return false
-	}
-
-	if converted.Members == nil {
-		converted.Members = []config.Member{}
-	}
+// groupUpToDate compares the group's attributes and settings; members
+// are handled separately. The live settings must already be reduced to
+// the configured ones.
+func groupUpToDate(configured config.Group, live config.Group) bool {
+	return reflect.DeepEqual(groupAttributes(configured), groupAttributes(live))
+}
 
-	if configured.Members == nil {
-		configured.Members = []config.Member{}
-	}
+// groupAttributes returns a copy of the group without any aliases,
+// members, membership mode and previous emails.
+func groupAttributes(group config.Group) config.Group {
+	group.Aliases = nil
+	group.Members = nil
+	group.MembershipMode = ""
+	group.PreviousEmails = nil
 
-	return reflect.DeepEqual(configured, converted)
+	return group
 }
 
-func memberUpToDate(configured config.Member, live *directoryv1.Member) bool {
-	converted := config.ToConfigGroupMember(live)
+// memberUpToDate compares the member's role and delivery setting, if
+// one is configured. The type is determined by GSuite and cannot be
+// changed for an existing member, the membership period only exists in
+// the configuration.
+func memberUpToDate(configured config.Member, live config.Member) bool {
+	configured.Type = live.Type
+	configured.Since = ""
+	configured.Expires = ""
+
+	if configured.Delivery == "" {
+		configured.Delivery = live.Delivery
+	}
 
-	return reflect.DeepEqual(configured, converted)
+	return reflect.DeepEqual(configured, live)
 }
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// sensitiveFields are never printed in diffs.
+var sensitiveFields = sets.NewString("password", "passwordHash")
+
+// FieldDiff is a single changed field, identified by its path of YAML
+// keys, e.g. "employeeInfo.costCenter".
+type FieldDiff struct {
+	Path   string
+	Before interface{}
+	After  interface{}
+}
+
+func (d FieldDiff) String() string {
+	if sensitiveFields.Has(d.Path) {
+		return fmt.Sprintf("%s: (changed)", d.Path)
+	}
+
+	return fmt.Sprintf("%s: %s → %s", d.Path, formatDiffValue(d.Before), formatDiffValue(d.After))
+}
+
+// Diff returns the changed fields of an update or rename action's org
+// unit, user, group or member. Other actions have no field-level diff.
+func (a *Action) Diff() []FieldDiff {
+	if (a.Operation != UpdateOperation && a.Operation != RenameOperation) || a.Before == nil || a.After == nil {
+		return nil
+	}
+
+	var before, after interface{}
+
+	switch {
+	case a.Before.OrgUnit != nil && a.After.OrgUnit != nil:
+		before, after = *a.Before.OrgUnit, *a.After.OrgUnit
+	case a.Before.User != nil && a.After.User != nil:
+		before, after = *a.Before.User, *a.After.User
+	case a.Before.Group != nil && a.After.Group != nil:
+		before, after = *a.Before.Group, *a.After.Group
+	case a.Before.Member != nil && a.After.Member != nil:
+		before, after = *a.Before.Member, *a.After.Member
+	default:
+		return nil
+	}
+
+	return diffStructs("", reflect.ValueOf(before), reflect.ValueOf(after))
+}
+
+func diffStructs(prefix string, before reflect.Value, after reflect.Value) []FieldDiff {
+	diffs := []FieldDiff{}
+	structType := before.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		// fields that only exist in plans are named by their JSON key
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			name = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		beforeField := before.Field(i)
+		afterField := after.Field(i)
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			// inlined structs like the group settings share the prefix
+			if field.Anonymous {
+				path = prefix
+			}
+
+			diffs = append(diffs, diffStructs(path, beforeField, afterField)...)
+			continue
+
+		case reflect.Slice:
+			// nil and empty slices are equivalent
+			if beforeField.Len() == 0 && afterField.Len() == 0 {
+				continue
+			}
+		}
+
+		if !reflect.DeepEqual(beforeField.Interface(), afterField.Interface()) {
+			diffs = append(diffs, FieldDiff{
+				Path:   path,
+				Before: beforeField.Interface(),
+				After:  afterField.Interface(),
+			})
+		}
+	}
+
+	return diffs
+}
+
+func formatDiffValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+
+	case []string:
+		quoted := []string{}
+		for _, s := range v {
+			quoted = append(quoted, fmt.Sprintf("%q", s))
+		}
+
+		return "[" + strings.Join(quoted, ", ") + "]"
+
+	case *bool:
+		if v == nil {
+			return "(unset)"
+		}
+
+		return fmt.Sprintf("%v", *v)
+
+	case *string:
+		if v == nil {
+			return "(unset)"
+		}
+
+		return fmt.Sprintf("%q", *v)
+
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func TestActionDiff(t *testing.T) {
+	testcases := []struct {
+		name     string
+		action   Action
+		expected []string
+	}{
+		{
+			name: "changed and nested fields",
+			action: Action{
+				Operation: UpdateOperation,
+				Before: &Value{User: &config.User{
+					FirstName: "Jane",
+					Phones:    []string{"+4912345"},
+				}},
+				After: &Value{User: &config.User{
+					FirstName: "Janet",
+					Phones:    []string{"+4912345", "+4967890"},
+					Employee:  config.Employee{CostCenter: "A"},
+				}},
+			},
+			expected: []string{
+				`givenName: "Jane" → "Janet"`,
+				`phones: ["+4912345"] → ["+4912345", "+4967890"]`,
+				`employeeInfo.costCenter: "" → "A"`,
+			},
+		},
+		{
+			name: "empty and missing slices are equal",
+			action: Action{
+				Operation: UpdateOperation,
+				Before:    &Value{User: &config.User{Phones: []string{}}},
+				After:     &Value{User: &config.User{}},
+			},
+			expected: []string{},
+		},
+		{
+			name: "passwords are never printed",
+			action: Action{
+				Operation: UpdateOperation,
+				Before:    &Value{User: &config.User{}},
+				After: &Value{User: &config.User{
+					PasswordHash: "$6$salt$hash",
+					HashFunction: config.PasswordHashFunction,
+				}},
+			},
+			expected: []string{
+				`passwordHash: (changed)`,
+				`hashFunction: "" → "crypt"`,
+			},
+		},
+		{
+			name: "org units",
+			action: Action{
+				Operation: UpdateOperation,
+				Before:    &Value{OrgUnit: &config.OrgUnit{Name: "Engineering"}},
+				After:     &Value{OrgUnit: &config.OrgUnit{Name: "Engineering", Description: "Engineers"}},
+			},
+			expected: []string{
+				`description: "" → "Engineers"`,
+			},
+		},
+		{
+			name: "only updates have diffs",
+			action: Action{
+				Operation: CreateOperation,
+				After:     &Value{User: &config.User{FirstName: "Jane"}},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var diffs []string
+			if fieldDiffs := tc.action.Diff(); fieldDiffs != nil {
+				diffs = []string{}
+				for _, diff := range fieldDiffs {
+					diffs = append(diffs, diff.String())
+				}
+			}
+
+			if !reflect.DeepEqual(diffs, tc.expected) {
+				t.Fatalf("Expected diffs\n%q\nbut got\n%q", tc.expected, diffs)
+			}
+		})
+	}
+}
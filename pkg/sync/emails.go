@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// The canonicalize* functions bring the emails of live resources into
+// the same canonical form as those in the configuration, so that both
+// can be compared as plain strings.
+
+func canonicalizeUsers(users []*directoryv1.User) {
+	for _, user := range users {
+		user.PrimaryEmail = config.CanonicalEmail(user.PrimaryEmail)
+
+		for i, alias := range user.Aliases {
+			user.Aliases[i] = config.CanonicalEmail(alias)
+		}
+	}
+}
+
+func canonicalizeGroups(groups []*directoryv1.Group) {
+	for _, group := range groups {
+		group.Email = config.CanonicalEmail(group.Email)
+
+		for i, alias := range group.Aliases {
+			group.Aliases[i] = config.CanonicalEmail(alias)
+		}
+	}
+}
+
+func canonicalizeEmails(emails []string) {
+	for i, email := range emails {
+		emails[i] = config.CanonicalEmail(email)
+	}
+}
+
+func canonicalizeMembers(members []*directoryv1.Member) {
+	for _, member := range members {
+		member.Email = config.CanonicalEmail(member.Email)
+	}
+}
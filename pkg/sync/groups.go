@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"time"
 
 	directoryv1 "google.golang.org/api/admin/directory/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -29,119 +30,272 @@ import (
 	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func SyncGroups(
+// PlanGroups adds the actions required to sync groups, their settings
+// and members to the plan.
+func PlanGroups(
 	ctx context.Context,
-	directorySrv *glib.DirectoryService,
-	groupsSettingsSrv *glib.GroupsSettingsService,
+	plan *Plan,
+	directorySrv glib.DirectoryClient,
+	groupsSettingsSrv glib.GroupsSettingsClient,
 	cfg *config.Config,
-	confirm bool,
-) (bool, error) {
-	changes := false
-
+	selection *config.Selection,
+	concurrency int,
+) error {
 	log.Println("⇄ Syncing groups…")
 
 	liveGroups, err := directorySrv.ListGroups(ctx)
 	if err != nil {
-		return changes, err
+		return err
 	}
 
-	liveGroupEmails := sets.NewString()
+	canonicalizeGroups(liveGroups)
 
 	sort.Slice(liveGroups, func(i, j int) bool {
 		return liveGroups[i].Email < liveGroups[j].Email
 	})
 
+	liveGroups = withoutIgnoredGroups(plan, &cfg.Ignore, liveGroups)
+	plan.LiveCounts[GroupResource] = len(liveGroups)
+
+	liveGroups, expectedGroups := selectGroups(selection, liveGroups, cfg.Groups)
+	liveGroupEmails := sets.NewString()
+
+	renames := groupRenames(liveGroups, expectedGroups)
+
+	// members and settings are only needed for groups that are kept
+	expectedGroupEmails := sets.NewString()
+	for _, expectedGroup := range expectedGroups {
+		expectedGroupEmails.Insert(expectedGroup.Email)
+	}
+
+	keptGroups := []*directoryv1.Group{}
+	for _, liveGroup := range liveGroups {
+		if expectedGroupEmails.Has(liveGroup.Email) || renames[liveGroup.Email] != "" {
+			keptGroups = append(keptGroups, liveGroup)
+		}
+	}
+
+	groupDetails, err := glib.FetchGroupDetails(ctx, directorySrv, groupsSettingsSrv, keptGroups, concurrency)
+	if err != nil {
+		return err
+	}
+
+	for _, details := range groupDetails {
+		canonicalizeMembers(details.Members)
+		canonicalizeEmails(details.Aliases)
+	}
+
+	// memberships follow renamed users and groups, so refer to them by
+	// their new email
+	renamedMembers := plan.renames(UserResource)
+	for oldEmail, newEmail := range renames {
+		renamedMembers[oldEmail] = newEmail
+	}
+
+	for _, details := range groupDetails {
+		for i, member := range details.Members {
+			if newEmail, ok := renamedMembers[member.Email]; ok {
+				renamedMember := *member
+				renamedMember.Email = newEmail
+				details.Members[i] = &renamedMember
+			}
+		}
+	}
+
+	// emails of the live groups after all renames
 	for _, liveGroup := range liveGroups {
-		liveGroupEmails.Insert(liveGroup.Email)
+		if newEmail, ok := renames[liveGroup.Email]; ok {
+			liveGroupEmails.Insert(newEmail)
+		} else {
+			liveGroupEmails.Insert(liveGroup.Email)
+		}
+	}
+
+	// new groups are created first, so they can become members of others
+	for _, expectedGroup := range groupCreationOrder(expectedGroups, liveGroupEmails) {
+		expectedGroup := expectedGroup
+		after := groupAttributes(expectedGroup)
+
+		plan.add(Action{
+			Resource:  GroupResource,
+			Operation: CreateOperation,
+			Name:      expectedGroup.Email,
+			After:     &Value{Group: &after},
+		})
+
+		planGroupAliases(plan, &expectedGroup, nil)
+		planGroupMembers(plan, &expectedGroup, "", nil)
+	}
+
+	for _, liveGroup := range liveGroups {
+		// the email after a possible rename
+		email := liveGroup.Email
+		if newEmail, ok := renames[email]; ok {
+			email = newEmail
+		}
 
 		found := false
 
-		for _, expectedGroup := range cfg.Groups {
-			if expectedGroup.Email == liveGroup.Email {
+		for _, expectedGroup := range expectedGroups {
+			if expectedGroup.Email == email {
 				found = true
 
-				liveMembers, err := directorySrv.ListMembers(ctx, liveGroup)
+				details := groupDetails[liveGroup.Email]
+				liveMembers := details.Members
+
+				currentGroup, err := config.ToConfigGroup(liveGroup, details.Settings, liveMembers, details.Aliases)
 				if err != nil {
-					return changes, fmt.Errorf("failed to fetch members: %v", err)
+					return fmt.Errorf("failed to convert group %s: %v", liveGroup.Email, err)
 				}
 
-				liveSettings, err := groupsSettingsSrv.GetSettings(ctx, liveGroup.Email)
-				if err != nil {
-					return changes, fmt.Errorf("failed to fetch group settings: %v", err)
+				// settings that are not configured are not managed
+				currentGroup.GroupSettings = currentGroup.GroupSettings.OnlyConfigured(expectedGroup.GroupSettings)
+
+				liveAliases := details.Aliases
+				if email != liveGroup.Email {
+					// GSuite keeps the old email as an alias
+					liveAliases = append(liveAliases, liveGroup.Email)
 				}
 
-				if groupUpToDate(expectedGroup, liveGroup, liveMembers, liveSettings) {
+				infoUpToDate := groupUpToDate(expectedGroup, currentGroup)
+				aliasesUpToDate := expectedGroupAliases(&expectedGroup, liveAliases).Equal(sets.NewString(liveAliases...))
+				membersUpToDate := membersUpToDate(&expectedGroup, liveMembers)
+
+				if infoUpToDate && aliasesUpToDate && membersUpToDate {
 					// no update needed
-					log.Printf("  ✓ %s", expectedGroup.Email)
-				} else {
-					// update it
-					changes = true
-					log.Printf("  ✎ %s", expectedGroup.Email)
-
-					group, settings := config.ToGSuiteGroup(&expectedGroup)
-
-					if confirm {
-						group, err = directorySrv.UpdateGroup(ctx, liveGroup, group)
-						if err != nil {
-							return changes, fmt.Errorf("failed to update group: %v", err)
-						}
-
-						if _, err := groupsSettingsSrv.UpdateSettings(ctx, group, settings); err != nil {
-							return changes, fmt.Errorf("failed to update group settings: %v", err)
-						}
+					plan.upToDate(expectedGroup.Email)
+					break
+				}
+
+				// update it
+				if !infoUpToDate {
+					before := groupAttributes(currentGroup)
+					after := groupAttributes(expectedGroup)
+
+					action := Action{
+						Resource:  GroupResource,
+						Operation: UpdateOperation,
+						Name:      expectedGroup.Email,
+						ID:        liveGroup.Id,
+						Before:    &Value{Group: &before},
+						After:     &Value{Group: &after},
 					}
 
-					if err := syncGroupMembers(ctx, directorySrv, &expectedGroup, group, liveMembers, confirm); err != nil {
-						return changes, fmt.Errorf("failed to sync members: %v", err)
+					if email != liveGroup.Email {
+						action.Operation = RenameOperation
+						action.OldName = liveGroup.Email
 					}
+
+					plan.add(action)
 				}
 
+				planGroupAliases(plan, &expectedGroup, liveAliases)
+				planGroupMembers(plan, &expectedGroup, liveGroup.Id, liveMembers)
+
 				break
 			}
 		}
 
 		if !found {
-			changes = true
-			log.Printf("  - %s", liveGroup.Email)
-
-			if confirm {
-				if err := directorySrv.DeleteGroup(ctx, liveGroup); err != nil {
-					return changes, fmt.Errorf("failed to delete group: %v", err)
-				}
+			if cfg.Protected.IsGroupProtected(liveGroup.Email) {
+				plan.protected(liveGroup.Email)
+				continue
 			}
+
+			plan.add(Action{
+				Resource:  GroupResource,
+				Operation: DeleteOperation,
+				Name:      liveGroup.Email,
+				ID:        liveGroup.Id,
+				Before: &Value{Group: &config.Group{
+					Name:        liveGroup.Name,
+					Email:       liveGroup.Email,
+					Description: liveGroup.Description,
+				}},
+			})
 		}
 	}
 
-	for _, expectedGroup := range cfg.Groups {
+	return nil
+}
+
+// groupCreationOrder returns the groups that do not exist yet, with
+// nested groups before the groups they are members of.
+func groupCreationOrder(expectedGroups []config.Group, liveGroupEmails sets.String) []config.Group {
+	newGroups := map[string]config.Group{}
+	for _, expectedGroup := range expectedGroups {
 		if !liveGroupEmails.Has(expectedGroup.Email) {
-			changes = true
-			log.Printf("  + %s", expectedGroup.Email)
+			newGroups[expectedGroup.Email] = expectedGroup
+		}
+	}
 
-			group, settings := config.ToGSuiteGroup(&expectedGroup)
+	ordered := []config.Group{}
+	visited := sets.NewString()
 
-			if confirm {
-				group, err = directorySrv.CreateGroup(ctx, group)
-				if err != nil {
-					return changes, fmt.Errorf("failed to create group: %v", err)
-				}
+	var visit func(group config.Group)
+	visit = func(group config.Group) {
+		if visited.Has(group.Email) {
+			return
+		}
+		visited.Insert(group.Email)
 
-				if _, err := groupsSettingsSrv.UpdateSettings(ctx, group, settings); err != nil {
-					return changes, fmt.Errorf("failed to update group settings: %v", err)
-				}
+		for _, member := range group.Members {
+			if nested, ok := newGroups[member.Email]; ok {
+				visit(nested)
 			}
+		}
+
+		ordered = append(ordered, group)
+	}
+
+	for _, expectedGroup := range expectedGroups {
+		if _, ok := newGroups[expectedGroup.Email]; ok {
+			visit(expectedGroup)
+		}
+	}
 
-			if err := syncGroupMembers(ctx, directorySrv, &expectedGroup, group, nil, confirm); err != nil {
-				return changes, fmt.Errorf("failed to sync members: %v", err)
+	return ordered
+}
+
+// groupRenames returns the new emails of all live groups that are
+// configured with their email as a previous email, keyed by their live
+// email.
+func groupRenames(liveGroups []*directoryv1.Group, expectedGroups []config.Group) map[string]string {
+	liveEmails := sets.NewString()
+	for _, liveGroup := range liveGroups {
+		liveEmails.Insert(liveGroup.Email)
+	}
+
+	expectedEmails := sets.NewString()
+	for _, expectedGroup := range expectedGroups {
+		expectedEmails.Insert(expectedGroup.Email)
+	}
+
+	renames := map[string]string{}
+
+	for _, expectedGroup := range expectedGroups {
+		if liveEmails.Has(expectedGroup.Email) {
+			continue
+		}
+
+		for _, previousEmail := range expectedGroup.PreviousEmails {
+			if liveEmails.Has(previousEmail) && !expectedEmails.Has(previousEmail) && renames[previousEmail] == "" {
+				renames[previousEmail] = expectedGroup.Email
+				break
 			}
 		}
 	}
 
-	return changes, nil
+	return renames
 }
 
+// getConfiguredMember returns the configured member for the live member,
+// or nil if there is none or its membership is not active today.
 func getConfiguredMember(group *config.Group, member *directoryv1.Member) *config.Member {
+	now := time.Now()
+
 	for _, m := range group.Members {
-		if m.Email == member.Email {
+		if m.Email == member.Email && m.Active(now) {
 			return &m
 		}
 	}
@@ -149,53 +303,172 @@ func getConfiguredMember(group *config.Group, member *directoryv1.Member) *confi
 	return nil
 }
 
-func syncGroupMembers(
-	ctx context.Context,
-	directorySrv *glib.DirectoryService,
+// managesMember returns true if the live member is configured or managed
+// according to the group's membership mode.
+func managesMember(expectedGroup *config.Group, liveMember *directoryv1.Member) bool {
+	return getConfiguredMember(expectedGroup, liveMember) != nil || expectedGroup.ManagesMember(config.ToConfigGroupMember(liveMember))
+}
+
+func membersUpToDate(expectedGroup *config.Group, liveMembers []*directoryv1.Member) bool {
+	liveMemberEmails := sets.NewString()
+
+	for _, liveMember := range liveMembers {
+		liveMemberEmails.Insert(liveMember.Email)
+
+		if !managesMember(expectedGroup, liveMember) {
+			continue
+		}
+
+		expectedMember := getConfiguredMember(expectedGroup, liveMember)
+		if expectedMember == nil || !memberUpToDate(*expectedMember, config.ToConfigGroupMember(liveMember)) {
+			return false
+		}
+	}
+
+	now := time.Now()
+	for _, expectedMember := range expectedGroup.Members {
+		if expectedMember.Active(now) && !liveMemberEmails.Has(expectedMember.Email) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func planGroupAliases(plan *Plan, expectedGroup *config.Group, liveAliases []string) {
+	expectedAliases := expectedGroupAliases(expectedGroup, liveAliases)
+	liveAliasesSet := sets.NewString(liveAliases...)
+
+	for _, liveAlias := range liveAliases {
+		if !expectedAliases.Has(liveAlias) {
+			plan.add(Action{
+				Resource:  GroupAliasResource,
+				Operation: DeleteOperation,
+				Name:      liveAlias,
+				Parent:    expectedGroup.Email,
+			})
+		}
+	}
+
+	for _, expectedAlias := range expectedAliases.List() {
+		if !liveAliasesSet.Has(expectedAlias) {
+			plan.add(Action{
+				Resource:  GroupAliasResource,
+				Operation: CreateOperation,
+				Name:      expectedAlias,
+				Parent:    expectedGroup.Email,
+			})
+		}
+	}
+}
+
+// expectedGroupAliases returns the configured aliases of a group plus
+// those of its previous emails that are live aliases, so that mail sent
+// to the old address of a renamed group is still delivered.
+func expectedGroupAliases(expectedGroup *config.Group, liveAliases []string) sets.String {
+	previousEmails := sets.NewString(expectedGroup.PreviousEmails...)
+
+	expectedAliases := sets.NewString(expectedGroup.Aliases...)
+	for _, liveAlias := range liveAliases {
+		if previousEmails.Has(liveAlias) {
+			expectedAliases.Insert(liveAlias)
+		}
+	}
+
+	return expectedAliases
+}
+
+func planGroupMembers(
+	plan *Plan,
 	expectedGroup *config.Group,
-	liveGroup *directoryv1.Group,
+	groupID string,
 	liveMembers []*directoryv1.Member,
-	confirm bool,
-) error {
+) {
 	liveMemberEmails := sets.NewString()
 
 	for _, liveMember := range liveMembers {
 		liveMemberEmails.Insert(liveMember.Email)
 
-		expectedMember := getConfiguredMember(expectedGroup, liveMember)
+		if !managesMember(expectedGroup, liveMember) {
+			continue
+		}
 
-		if expectedMember == nil {
-			log.Printf("    - %s", liveMember.Email)
+		currentMember := config.ToConfigGroupMember(liveMember)
+		expectedMember := getConfiguredMember(expectedGroup, liveMember)
 
-			if confirm {
-				if err := directorySrv.RemoveMember(ctx, liveGroup, liveMember); err != nil {
-					return fmt.Errorf("unable to remove member: %v", err)
-				}
+		if expectedMember == nil && expectedGroup.MembershipMode == config.MembershipModeOwnersOnly {
+			// only the roles are managed, so owners and managers that are
+			// not configured are demoted instead of removed
+			after := currentMember
+			after.Role = config.MemberRoleMember
+
+			plan.add(Action{
+				Resource:  MemberResource,
+				Operation: UpdateOperation,
+				Name:      memberName(currentMember),
+				Parent:    expectedGroup.Email,
+				ID:        liveMember.Id,
+				ParentID:  groupID,
+				Before:    &Value{Member: &currentMember},
+				After:     &Value{Member: &after},
+			})
+		} else if expectedMember == nil {
+			plan.add(Action{
+				Resource:  MemberResource,
+				Operation: DeleteOperation,
+				Name:      memberName(currentMember),
+				Parent:    expectedGroup.Email,
+				ID:        liveMember.Id,
+				ParentID:  groupID,
+				Before:    &Value{Member: &currentMember},
+			})
+		} else if !memberUpToDate(*expectedMember, currentMember) {
+			after := *expectedMember
+			after.Type = currentMember.Type
+			after.Since = ""
+			after.Expires = ""
+
+			if after.Delivery == "" {
+				after.Delivery = currentMember.Delivery
 			}
-		} else if !memberUpToDate(*expectedMember, liveMember) {
-			log.Printf("    ✎ %s", liveMember.Email)
 
-			if confirm {
-				member := config.ToGSuiteGroupMember(expectedMember, liveMember)
-				if err := directorySrv.UpdateMembership(ctx, liveGroup, member); err != nil {
-					return fmt.Errorf("unable to update membership: %v", err)
-				}
-			}
+			plan.add(Action{
+				Resource:  MemberResource,
+				Operation: UpdateOperation,
+				Name:      memberName(currentMember),
+				Parent:    expectedGroup.Email,
+				ID:        liveMember.Id,
+				ParentID:  groupID,
+				Before:    &Value{Member: &currentMember},
+				After:     &Value{Member: &after},
+			})
 		}
 	}
 
+	// members are only added while their membership is active
+	now := time.Now()
 	for _, expectedMember := range expectedGroup.Members {
-		if !liveMemberEmails.Has(expectedMember.Email) {
-			log.Printf("    + %s", expectedMember.Email)
-
-			if confirm {
-				member := config.ToGSuiteGroupMember(&expectedMember, nil)
-				if err := directorySrv.AddNewMember(ctx, liveGroup, member); err != nil {
-					return fmt.Errorf("unable to add member: %v", err)
-				}
-			}
+		if expectedMember.Active(now) && !liveMemberEmails.Has(expectedMember.Email) {
+			expectedMember := expectedMember
+
+			plan.add(Action{
+				Resource:  MemberResource,
+				Operation: CreateOperation,
+				Name:      memberName(expectedMember),
+				Parent:    expectedGroup.Email,
+				ParentID:  groupID,
+				After:     &Value{Member: &expectedMember},
+			})
 		}
 	}
+}
 
-	return nil
+// memberName returns the member's email, or its type for members that
+// have no email.
+func memberName(member config.Member) string {
+	if member.Email == "" {
+		return member.Type
+	}
+
+	return member.Email
 }
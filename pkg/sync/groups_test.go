@@ -0,0 +1,524 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+const testGroupUsers = `
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+`
+
+func TestPlanGroups(t *testing.T) {
+	runTestcases(t, []testcase{
+		{
+			name: "create with aliases and members",
+			live: "organization: example\n" + testGroupUsers,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    aliases: [crew@example.com]
+    members:
+      - email: jane@example.com
+        role: OWNER
+      - email: bob@example.com
+      - type: CUSTOMER
+`,
+			expected: []string{
+				"create group team@example.com",
+				"create groupalias team@example.com/crew@example.com",
+				"create member team@example.com/CUSTOMER",
+				"create member team@example.com/bob@example.com",
+				"create member team@example.com/jane@example.com",
+			},
+		},
+		{
+			name: "create member groups first",
+			live: "organization: example\n" + testGroupUsers,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: All
+    email: all@example.com
+    members:
+      - email: team@example.com
+        type: GROUP
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+`,
+			expected: []string{
+				"create group team@example.com",
+				"create member team@example.com/jane@example.com",
+				"create group all@example.com",
+				"create member all@example.com/team@example.com",
+			},
+		},
+		{
+			name: "update roles and remove members",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+      - email: bob@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+        role: OWNER
+`,
+			expected: []string{
+				"delete member team@example.com/bob@example.com",
+				"update member team@example.com/jane@example.com",
+			},
+		},
+		{
+			name: "update configured delivery settings",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+        delivery: DIGEST
+      - email: bob@example.com
+        delivery: DIGEST
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+      - email: bob@example.com
+        delivery: none
+`,
+			expected: []string{
+				"update member team@example.com/bob@example.com",
+			},
+		},
+		{
+			name: "update settings",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    whoCanJoin: ALL_IN_DOMAIN_CAN_JOIN
+`,
+			expected: []string{
+				"update group team@example.com",
+			},
+		},
+		{
+			name: "update and clear extended settings",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    allowWebPosting: false
+    includeCustomFooter: true
+    customFooterText: Sent via the team list
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    whoCanModerateContent: OWNERS_ONLY
+`,
+			expected: []string{
+				"update group team@example.com",
+			},
+		},
+		{
+			name: "only reset the basic settings if unconfigured",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    spamModerationLevel: REJECT
+    whoCanJoin: ALL_IN_DOMAIN_CAN_JOIN
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+`,
+			expected: []string{
+				"update group team@example.com",
+			},
+		},
+		{
+			name: "leave unconfigured extended settings alone",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    spamModerationLevel: REJECT
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+`,
+			expected: nil,
+		},
+		{
+			name: "apply group defaults",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+  - name: Staff
+    email: staff@example.com
+    whoCanViewMembers: ALL_MANAGERS_CAN_VIEW
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groupDefaults:
+  whoCanViewMembers: ALL_IN_DOMAIN_CAN_VIEW
+groups:
+  - name: Team
+    email: team@example.com
+  - name: Staff
+    email: staff@example.com
+    whoCanViewMembers: ALL_MANAGERS_CAN_VIEW
+`,
+			expected: []string{
+				"update group team@example.com",
+			},
+		},
+		{
+			name: "leave unconfigured settings alone with the explicit policy",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    allowExternalMembers: true
+    customFooterText: Sent via the team list
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groupSettingsPolicy: explicit
+groups:
+  - name: Team
+    email: team@example.com
+`,
+			expected: nil,
+		},
+		{
+			name: "reset configured settings with the explicit policy",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    allowExternalMembers: true
+    customFooterText: Sent via the team list
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groupSettingsPolicy: explicit
+groups:
+  - name: Team
+    email: team@example.com
+    allowExternalMembers: false
+`,
+			expected: []string{
+				"update group team@example.com",
+			},
+		},
+		{
+			name: "update aliases",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    aliases: [crew@example.com]
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    aliases: [staff@example.com]
+`,
+			expected: []string{
+				"delete groupalias team@example.com/crew@example.com",
+				"create groupalias team@example.com/staff@example.com",
+			},
+		},
+		{
+			name: "rename and keep the old address as an alias",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Crew
+    email: crew@example.com
+    previousEmails: [team@example.com]
+    members:
+      - email: jane@example.com
+`,
+			expected: []string{
+				"rename group crew@example.com",
+			},
+		},
+		{
+			name: "keep unconfigured members of additive groups",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+      - email: bob@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    membershipMode: additive
+    members:
+      - email: jane@example.com
+`,
+			expected: nil,
+		},
+		{
+			name: "add and update members of additive groups",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    membershipMode: additive
+    members:
+      - email: jane@example.com
+        role: OWNER
+      - email: bob@example.com
+`,
+			expected: []string{
+				"update member team@example.com/jane@example.com",
+				"create member team@example.com/bob@example.com",
+			},
+		},
+		{
+			name: "demote unconfigured owners of ownersOnly groups",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+        role: OWNER
+      - email: bob@example.com
+        role: MANAGER
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    membershipMode: ownersOnly
+    members:
+      - email: jane@example.com
+        role: OWNER
+`,
+			expected: []string{
+				"update member team@example.com/bob@example.com",
+			},
+		},
+		{
+			name: "delete unconfigured groups",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+  - name: Legacy
+    email: legacy@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+`,
+			expected: []string{
+				"delete group legacy@example.com",
+			},
+		},
+		{
+			name: "keep protected groups",
+			live: "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+  - name: Legacy
+    email: legacy@example.com
+`,
+			config: "organization: example\n" + testGroupUsers + `
+protected:
+  groups: [legacy@example.com]
+`,
+			expected: []string{
+				"delete group team@example.com",
+			},
+		},
+	})
+}
+
+func TestPlanGroupsConcurrently(t *testing.T) {
+	clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+
+	live := "organization: example\n" + testGroupUsers + "groups:\n"
+	configured := "organization: example\n" + testGroupUsers + "groups:\n"
+
+	for i := 0; i < 10; i++ {
+		live += fmt.Sprintf("  - name: Group %d\n    email: group%d@example.com\n    members:\n      - email: jane@example.com\n", i, i)
+		configured += fmt.Sprintf("  - name: Group %d\n    email: group%d@example.com\n    members:\n      - email: bob@example.com\n", i, i)
+	}
+
+	syncConfig(t, clients, loadConfig(t, live))
+
+	cfg := loadConfig(t, configured)
+	ctx := context.Background()
+
+	var expected []string
+
+	for _, concurrency := range []int{1, 4, 16} {
+		plan := NewPlan(testOrganization)
+		if err := PlanGroups(ctx, plan, clients.directory, clients.groupsSettings, cfg, nil, concurrency); err != nil {
+			t.Fatalf("Failed to plan groups: %v", err)
+		}
+
+		actions := describeActions(plan)
+		if expected == nil {
+			expected = actions
+		}
+
+		if !reflect.DeepEqual(actions, expected) {
+			t.Fatalf("Expected the same actions with %d workers\n%q\nbut got\n%q", concurrency, expected, actions)
+		}
+	}
+
+	if len(expected) != 20 {
+		t.Fatalf("Expected 20 member actions, but got %q", expected)
+	}
+}
+
+func TestPlanGroupsMembershipPeriods(t *testing.T) {
+	yesterday := time.Now().AddDate(0, 0, -1).Format(config.MemberDateLayout)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(config.MemberDateLayout)
+
+	const live = "organization: example\n" + testGroupUsers + `
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+`
+
+	runTestcases(t, []testcase{
+		{
+			name: "remove expired members, even from additive groups",
+			live: live,
+			config: "organization: example\n" + testGroupUsers + fmt.Sprintf(`
+groups:
+  - name: Team
+    email: team@example.com
+    membershipMode: additive
+    members:
+      - email: jane@example.com
+        expires: %s
+`, yesterday),
+			expected: []string{
+				"delete member team@example.com/jane@example.com",
+			},
+		},
+		{
+			name: "keep members until their last day",
+			live: live,
+			config: "organization: example\n" + testGroupUsers + fmt.Sprintf(`
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+        expires: %s
+`, tomorrow),
+			expected: nil,
+		},
+		{
+			name: "add members once their membership starts",
+			live: live,
+			config: "organization: example\n" + testGroupUsers + fmt.Sprintf(`
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+      - email: bob@example.com
+        since: %s
+`, yesterday),
+			expected: []string{
+				"create member team@example.com/bob@example.com",
+			},
+		},
+		{
+			name: "do not add pending members yet",
+			live: live,
+			config: "organization: example\n" + testGroupUsers + fmt.Sprintf(`
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+      - email: bob@example.com
+        since: %s
+`, tomorrow),
+			expected: nil,
+		},
+	})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// guardedResources are the resource kinds whose deletions are limited.
+var guardedResources = []ResourceKind{OrgUnitResource, UserResource, GroupResource}
+
+// CheckDeletions returns an error if the plan deletes more than
+// maxDeletions resources of any kind, or more than maxPercent percent of
+// the live resources of any kind. Limits of 0 are ignored.
+func CheckDeletions(plan *Plan, maxDeletions int, maxPercent int) error {
+	deletions := map[ResourceKind]int{}
+	for _, action := range plan.Actions {
+		if action.Operation == DeleteOperation {
+			deletions[action.Resource]++
+		}
+	}
+
+	problems := []string{}
+
+	for _, kind := range guardedResources {
+		deleted := deletions[kind]
+		if deleted == 0 {
+			continue
+		}
+
+		if maxDeletions > 0 && deleted > maxDeletions {
+			problems = append(problems, fmt.Sprintf("%d %ss would be deleted, but at most %d are allowed", deleted, kind, maxDeletions))
+		}
+
+		if live := plan.LiveCounts[kind]; maxPercent > 0 && live > 0 && deleted*100 > maxPercent*live {
+			problems = append(problems, fmt.Sprintf("%d of %d %ss (%d%%) would be deleted, but at most %d%% are allowed", deleted, live, kind, deleted*100/live, maxPercent))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// CheckProtection returns an error if the plan deletes, suspends or moves
+// any protected org units, users or groups. Planning never removes
+// protected resources, but saved plans might have been created with a
+// different configuration or edited since.
+func CheckProtection(plan *Plan, protected *config.Protected) error {
+	problems := []string{}
+
+	for _, action := range plan.Actions {
+		switch action.Operation {
+		case DeleteOperation, SuspendOperation, MoveOperation:
+		default:
+			continue
+		}
+
+		var isProtected bool
+
+		switch action.Resource {
+		case OrgUnitResource:
+			isProtected = protected.IsOrgUnitProtected(action.Name)
+		case UserResource:
+			isProtected = protected.IsUserProtected(action.Name)
+		case GroupResource:
+			isProtected = protected.IsGroupProtected(action.Name)
+		}
+
+		if isProtected {
+			problems = append(problems, fmt.Sprintf("%s %s %s is protected", action.Operation, action.Resource, action.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func TestCheckDeletions(t *testing.T) {
+	deletions := func(kind ResourceKind, count int) []Action {
+		actions := []Action{}
+		for i := 0; i < count; i++ {
+			actions = append(actions, Action{Resource: kind, Operation: DeleteOperation})
+		}
+
+		return actions
+	}
+
+	testcases := []struct {
+		name         string
+		actions      []Action
+		liveCounts   map[ResourceKind]int
+		maxDeletions int
+		maxPercent   int
+		valid        bool
+	}{
+		{
+			name:         "below both limits",
+			actions:      deletions(UserResource, 2),
+			liveCounts:   map[ResourceKind]int{UserResource: 10},
+			maxDeletions: 5,
+			maxPercent:   50,
+			valid:        true,
+		},
+		{
+			name:         "too many deletions",
+			actions:      deletions(UserResource, 6),
+			liveCounts:   map[ResourceKind]int{UserResource: 100},
+			maxDeletions: 5,
+			valid:        false,
+		},
+		{
+			name:       "too large a percentage",
+			actions:    deletions(GroupResource, 3),
+			liveCounts: map[ResourceKind]int{GroupResource: 4},
+			maxPercent: 50,
+			valid:      false,
+		},
+		{
+			name:       "limits apply to each kind",
+			actions:    append(deletions(UserResource, 2), deletions(GroupResource, 2)...),
+			liveCounts: map[ResourceKind]int{UserResource: 4, GroupResource: 4},
+			maxPercent: 50,
+			valid:      true,
+		},
+		{
+			name:    "aliases and members are not limited",
+			actions: append(deletions(AliasResource, 10), deletions(MemberResource, 10)...),
+			liveCounts: map[ResourceKind]int{
+				UserResource:  1,
+				GroupResource: 1,
+			},
+			maxDeletions: 1,
+			maxPercent:   1,
+			valid:        true,
+		},
+		{
+			name:       "disabled limits",
+			actions:    deletions(OrgUnitResource, 10),
+			liveCounts: map[ResourceKind]int{OrgUnitResource: 10},
+			valid:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := NewPlan(testOrganization)
+			plan.Actions = tc.actions
+			plan.LiveCounts = tc.liveCounts
+
+			err := CheckDeletions(plan, tc.maxDeletions, tc.maxPercent)
+			if tc.valid && err != nil {
+				t.Fatalf("Expected the plan to pass, but got %v", err)
+			}
+
+			if !tc.valid && err == nil {
+				t.Fatalf("Expected the plan to be rejected")
+			}
+		})
+	}
+}
+
+func TestCheckProtection(t *testing.T) {
+	protected := &config.Protected{
+		Users:    []string{"admin@example.com"},
+		Groups:   []string{"all-*@example.com"},
+		OrgUnits: []string{"/Management"},
+	}
+
+	testcases := []struct {
+		name   string
+		action Action
+		valid  bool
+	}{
+		{
+			name:   "delete unprotected user",
+			action: Action{Resource: UserResource, Operation: DeleteOperation, Name: "jane@example.com"},
+			valid:  true,
+		},
+		{
+			name:   "delete protected user",
+			action: Action{Resource: UserResource, Operation: DeleteOperation, Name: "admin@example.com"},
+		},
+		{
+			name:   "suspend protected user",
+			action: Action{Resource: UserResource, Operation: SuspendOperation, Name: "admin@example.com"},
+		},
+		{
+			name:   "move protected user",
+			action: Action{Resource: UserResource, Operation: MoveOperation, Name: "admin@example.com"},
+		},
+		{
+			name:   "update protected user",
+			action: Action{Resource: UserResource, Operation: UpdateOperation, Name: "admin@example.com"},
+			valid:  true,
+		},
+		{
+			name:   "delete protected group",
+			action: Action{Resource: GroupResource, Operation: DeleteOperation, Name: "all-staff@example.com"},
+		},
+		{
+			name:   "delete protected org unit",
+			action: Action{Resource: OrgUnitResource, Operation: DeleteOperation, Name: "/Management"},
+		},
+		{
+			name:   "delete member of protected group",
+			action: Action{Resource: MemberResource, Operation: DeleteOperation, Name: "jane@example.com", Parent: "all-staff@example.com"},
+			valid:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := NewPlan(testOrganization)
+			plan.Actions = []Action{tc.action}
+
+			err := CheckProtection(plan, protected)
+			if tc.valid && err != nil {
+				t.Fatalf("Expected the plan to pass, but got %v", err)
+			}
+
+			if !tc.valid && err == nil {
+				t.Fatalf("Expected the plan to be rejected")
+			}
+		})
+	}
+}
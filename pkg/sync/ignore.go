@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// The withoutIgnored* functions remove the live resources that match the
+// configured ignore selectors and log each of them, so that it is clear
+// which resources GMan does not manage.
+
+func withoutIgnoredOrgUnits(plan *Plan, ignore *config.Ignore, orgUnits []*directoryv1.OrgUnit) []*directoryv1.OrgUnit {
+	result := []*directoryv1.OrgUnit{}
+	for _, orgUnit := range orgUnits {
+		if ignore.IsOrgUnitIgnored(orgUnit.OrgUnitPath) {
+			plan.ignored(orgUnit.OrgUnitPath)
+			continue
+		}
+
+		result = append(result, orgUnit)
+	}
+
+	return result
+}
+
+func withoutIgnoredUsers(plan *Plan, ignore *config.Ignore, users []*directoryv1.User) []*directoryv1.User {
+	result := []*directoryv1.User{}
+	for _, user := range users {
+		if ignore.IsUserIgnored(user.PrimaryEmail, user.OrgUnitPath) {
+			plan.ignored(user.PrimaryEmail)
+			continue
+		}
+
+		result = append(result, user)
+	}
+
+	return result
+}
+
+func withoutIgnoredGroups(plan *Plan, ignore *config.Ignore, groups []*directoryv1.Group) []*directoryv1.Group {
+	result := []*directoryv1.Group{}
+	for _, group := range groups {
+		if ignore.IsGroupIgnored(group.Email, group.Name) {
+			plan.ignored(group.Email)
+			continue
+		}
+
+		result = append(result, group)
+	}
+
+	return result
+}
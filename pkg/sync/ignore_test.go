@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+)
+
+func TestPlanIgnored(t *testing.T) {
+	runTestcases(t, []testcase{
+		{
+			name: "keep ignored resources",
+			live: `
+organization: example
+orgUnits:
+  - name: External
+  - name: Partners
+    parentOrgUnitPath: /External
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: CI
+    familyName: Bot
+    primaryEmail: bot-ci@example.com
+    orgUnitPath: /External/Partners
+groups:
+  - name: "[Auto] Alerts"
+    email: alerts@example.com
+`,
+			config: `
+organization: example
+ignore:
+  users:
+    - email: bot-*@example.com
+  groups:
+    - namePrefix: "[Auto]"
+  orgUnits: [/External]
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: nil,
+		},
+		{
+			name: "delete resources that are not ignored",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: CI
+    familyName: Bot
+    primaryEmail: ci-bot@example.com
+groups:
+  - name: Alerts
+    email: alerts@example.com
+`,
+			config: `
+organization: example
+ignore:
+  users:
+    - email: bot-*@example.com
+  groups:
+    - namePrefix: "[Auto]"
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"delete user ci-bot@example.com",
+				"delete group alerts@example.com",
+			},
+		},
+	})
+}
@@ -17,77 +17,35 @@ limitations under the License.
 package sync
 
 import (
-	"context"
-	"fmt"
-	"log"
-
-	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
-	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func userHasLicense(u *config.User, l config.License) bool {
-	for _, assigned := range u.Licenses {
-		if assigned == l.SkuId {
-			return true
-		}
-	}
-
-	return false
-}
-
-func sliceContainsLicense(licenses []config.License, identifier string) bool {
-	for _, license := range licenses {
-		if license.SkuId == identifier {
-			return true
-		}
-	}
-
-	return false
-}
-
-// syncUserLicenses provides logic for creating/deleting/updating licenses according to config file
-func syncUserLicenses(
-	ctx context.Context,
-	licenseSrv *glib.LicensingService,
-	expectedUser *config.User,
-	liveUser *directoryv1.User,
-	licenseStatus *glib.LicenseStatus,
-	confirm bool,
-) error {
-	expectedLicenses := expectedUser.Licenses
-	liveLicenses := []config.License{}
-
-	// in dry-run mode, there can be cases where there is no live user yet
-	if liveUser != nil {
-		liveLicenses = licenseStatus.GetLicensesForUser(liveUser)
-	}
+// planUserLicenses provides logic for assigning/unassigning licenses according to config file
+func planUserLicenses(plan *Plan, expectedUser *config.User, liveLicenses []string) {
+	expectedLicenses := sets.NewString(expectedUser.Licenses...)
+	liveLicensesSet := sets.NewString(liveLicenses...)
 
 	for _, liveLicense := range liveLicenses {
-		if !userHasLicense(expectedUser, liveLicense) {
-			log.Printf("    - license %s", liveLicense.Name)
-
-			if confirm {
-				if err := licenseSrv.UnassignLicense(ctx, liveUser, liveLicense); err != nil {
-					return fmt.Errorf("unable to assign license: %v", err)
-				}
-			}
+		if !expectedLicenses.Has(liveLicense) {
+			plan.add(Action{
+				Resource:  LicenseResource,
+				Operation: DeleteOperation,
+				Name:      liveLicense,
+				Parent:    expectedUser.PrimaryEmail,
+			})
 		}
 	}
 
-	for _, expectedLicense := range expectedLicenses {
-		if !sliceContainsLicense(liveLicenses, expectedLicense) {
-			license := licenseSrv.GetLicenseByName(expectedLicense)
-			log.Printf("    + license %s", license.Name)
-
-			if confirm {
-				if err := licenseSrv.AssignLicense(ctx, liveUser, *license); err != nil {
-					return fmt.Errorf("unable to assign license: %v", err)
-				}
-			}
+	for _, expectedLicense := range expectedUser.Licenses {
+		if !liveLicensesSet.Has(expectedLicense) {
+			plan.add(Action{
+				Resource:  LicenseResource,
+				Operation: CreateOperation,
+				Name:      expectedLicense,
+				Parent:    expectedUser.PrimaryEmail,
+			})
 		}
 	}
-
-	return nil
 }
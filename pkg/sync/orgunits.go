@@ -20,84 +20,201 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path"
+	"sort"
+	"strings"
 
+	directoryv1 "google.golang.org/api/admin/directory/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
 	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func SyncOrgUnits(
+// PlanOrgUnits adds the actions required to sync org units to the plan.
+// Org units are identified by their full path; org units that exist at
+// one of their previous paths are renamed or moved in place, taking their
+// children along. Parents are created before their children and deleted
+// after them.
+func PlanOrgUnits(
 	ctx context.Context,
-	directorySrv *glib.DirectoryService,
+	plan *Plan,
+	directorySrv glib.DirectoryClient,
 	cfg *config.Config,
-	confirm bool,
-) (bool, error) {
-	changes := false
+	selection *config.Selection,
+) error {
 	log.Println("⇄ Syncing organizational units…")
 
 	liveOrgUnits, err := directorySrv.ListOrgUnits(ctx)
 	if err != nil {
-		return changes, err
+		return err
 	}
 
-	liveNames := sets.NewString()
+	sort.Slice(liveOrgUnits, func(i, j int) bool {
+		return liveOrgUnits[i].OrgUnitPath < liveOrgUnits[j].OrgUnitPath
+	})
 
+	allLiveOrgUnits := liveOrgUnits
+
+	liveOrgUnits = withoutIgnoredOrgUnits(plan, &cfg.Ignore, liveOrgUnits)
+	plan.LiveCounts[OrgUnitResource] = len(liveOrgUnits)
+
+	liveOrgUnits, expectedOrgUnits := selectOrgUnits(selection, liveOrgUnits, cfg.OrgUnits)
+
+	sort.Slice(expectedOrgUnits, func(i, j int) bool {
+		return expectedOrgUnits[i].OrgUnitPath() < expectedOrgUnits[j].OrgUnitPath()
+	})
+
+	renames := orgUnitRenames(liveOrgUnits, expectedOrgUnits)
+
+	// live org units, keyed by their path after all renames
+	liveByPath := map[string]*directoryv1.OrgUnit{}
 	for _, liveOrgUnit := range liveOrgUnits {
-		liveNames.Insert(liveOrgUnit.Name)
-
-		found := false
-
-		for _, expectedOrgUnit := range cfg.OrgUnits {
-			if expectedOrgUnit.Name == liveOrgUnit.Name {
-				found = true
-
-				if orgUnitUpToDate(expectedOrgUnit, liveOrgUnit) {
-					// no update needed
-					log.Printf("  ✓ %s", expectedOrgUnit.Name)
-				} else {
-					// update it
-					changes = true
-					log.Printf("  ✎ %s", expectedOrgUnit.Name)
-
-					if confirm {
-						newOrgUnit := config.ToGSuiteOrgUnit(&expectedOrgUnit)
-						if err := directorySrv.UpdateOrgUnit(ctx, liveOrgUnit, newOrgUnit); err != nil {
-							return changes, fmt.Errorf("failed to update org unit: %v", err)
-						}
-					}
-				}
+		liveByPath[renamedPath(liveOrgUnit.OrgUnitPath, renames)] = liveOrgUnit
+	}
 
-				break
-			}
+	// all org units that exist after the sync, to check the parents of
+	// new org units against
+	remainingPaths := sets.NewString()
+
+	expectedPaths := sets.NewString()
+	for _, expectedOrgUnit := range expectedOrgUnits {
+		expectedPaths.Insert(expectedOrgUnit.OrgUnitPath())
+		remainingPaths.Insert(expectedOrgUnit.OrgUnitPath())
+	}
+
+	for _, liveOrgUnit := range allLiveOrgUnits {
+		remainingPaths.Insert(renamedPath(liveOrgUnit.OrgUnitPath, renames))
+	}
+
+	deletions := []Action{}
+
+	for _, liveOrgUnit := range liveOrgUnits {
+		orgUnitPath := renamedPath(liveOrgUnit.OrgUnitPath, renames)
+		if expectedPaths.Has(orgUnitPath) {
+			continue
 		}
 
-		if !found {
-			changes = true
-			log.Printf("  - %s", liveOrgUnit.Name)
+		if cfg.Protected.IsOrgUnitProtected(liveOrgUnit.OrgUnitPath) {
+			plan.protected(liveOrgUnit.OrgUnitPath)
+			continue
+		}
 
-			if confirm {
-				err := directorySrv.DeleteOrgUnit(ctx, liveOrgUnit)
-				if err != nil {
-					return changes, fmt.Errorf("failed to delete org unit: %v", err)
-				}
-			}
+		currentOrgUnit := config.ToConfigOrgUnit(liveOrgUnit)
+
+		remainingPaths.Delete(orgUnitPath)
+		deletions = append(deletions, Action{
+			Resource:  OrgUnitResource,
+			Operation: DeleteOperation,
+			Name:      liveOrgUnit.OrgUnitPath,
+			ID:        liveOrgUnit.OrgUnitId,
+			Before:    &Value{OrgUnit: &currentOrgUnit},
+		})
+	}
+
+	// parents before their children
+	for _, expectedOrgUnit := range expectedOrgUnits {
+		expectedOrgUnit := orgUnitAttributes(expectedOrgUnit)
+		orgUnitPath := expectedOrgUnit.OrgUnitPath()
+
+		parent := expectedOrgUnit.ParentOrgUnitPath
+		if parent != "/" && !remainingPaths.Has(parent) {
+			return fmt.Errorf("parent org unit %s of %s neither exists nor is configured", parent, orgUnitPath)
+		}
+
+		liveOrgUnit, exists := liveByPath[orgUnitPath]
+		if !exists {
+			plan.add(Action{
+				Resource:  OrgUnitResource,
+				Operation: CreateOperation,
+				Name:      orgUnitPath,
+				After:     &Value{OrgUnit: &expectedOrgUnit},
+			})
+
+			continue
 		}
+
+		currentOrgUnit := config.ToConfigOrgUnit(liveOrgUnit)
+
+		if renames[liveOrgUnit.OrgUnitPath] == orgUnitPath {
+			plan.add(Action{
+				Resource:  OrgUnitResource,
+				Operation: RenameOperation,
+				Name:      orgUnitPath,
+				OldName:   liveOrgUnit.OrgUnitPath,
+				ID:        liveOrgUnit.OrgUnitId,
+				Before:    &Value{OrgUnit: &currentOrgUnit},
+				After:     &Value{OrgUnit: &expectedOrgUnit},
+			})
+
+			continue
+		}
+
+		// the org unit might have moved along with a renamed parent
+		currentOrgUnit.ParentOrgUnitPath = path.Dir(orgUnitPath)
+
+		if orgUnitUpToDate(expectedOrgUnit, currentOrgUnit) {
+			// no update needed
+			plan.upToDate(orgUnitPath)
+			continue
+		}
+
+		// update it
+		plan.add(Action{
+			Resource:  OrgUnitResource,
+			Operation: UpdateOperation,
+			Name:      orgUnitPath,
+			ID:        liveOrgUnit.OrgUnitId,
+			Before:    &Value{OrgUnit: &currentOrgUnit},
+			After:     &Value{OrgUnit: &expectedOrgUnit},
+		})
+	}
+
+	// children before their parents
+	for i := len(deletions) - 1; i >= 0; i-- {
+		plan.add(deletions[i])
 	}
 
-	for _, expectedOrgUnit := range cfg.OrgUnits {
-		if !liveNames.Has(expectedOrgUnit.Name) {
-			changes = true
-			log.Printf("  + %s", expectedOrgUnit.Name)
+	return nil
+}
 
-			if confirm {
-				apiOrgUnit := config.ToGSuiteOrgUnit(&expectedOrgUnit)
-				if err := directorySrv.CreateOrgUnit(ctx, apiOrgUnit); err != nil {
-					return changes, fmt.Errorf("failed to create org unit: %v", err)
-				}
+// orgUnitRenames returns the new paths of all live org units that are
+// configured with them as a previous path, keyed by their live path.
+// Previous paths are ignored if the org unit already exists at its
+// configured path.
+func orgUnitRenames(liveOrgUnits []*directoryv1.OrgUnit, expectedOrgUnits []config.OrgUnit) map[string]string {
+	livePaths := sets.NewString()
+	for _, liveOrgUnit := range liveOrgUnits {
+		livePaths.Insert(liveOrgUnit.OrgUnitPath)
+	}
+
+	renames := map[string]string{}
+
+	for _, expectedOrgUnit := range expectedOrgUnits {
+		orgUnitPath := expectedOrgUnit.OrgUnitPath()
+		if livePaths.Has(orgUnitPath) {
+			continue
+		}
+
+		for _, previousPath := range expectedOrgUnit.PreviousPaths {
+			if livePaths.Has(previousPath) {
+				renames[previousPath] = orgUnitPath
+				break
 			}
 		}
 	}
 
-	return changes, nil
+	return renames
+}
+
+// renamedPath returns the path of an org unit after it or the closest of
+// its ancestors has been renamed.
+func renamedPath(orgUnitPath string, renames map[string]string) string {
+	for oldPath := orgUnitPath; oldPath != "/" && oldPath != "."; oldPath = path.Dir(oldPath) {
+		if newPath, ok := renames[oldPath]; ok {
+			return newPath + strings.TrimPrefix(orgUnitPath, oldPath)
+		}
+	}
+
+	return orgUnitPath
 }
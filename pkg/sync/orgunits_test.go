@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+func TestPlanOrgUnits(t *testing.T) {
+	runTestcases(t, []testcase{
+		{
+			name: "create parents before their children",
+			config: `
+organization: example
+orgUnits:
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+  - name: Engineering
+`,
+			expected: []string{
+				"create orgunit /Engineering",
+				"create orgunit /Engineering/Platform",
+			},
+		},
+		{
+			name: "update the description",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+    description: Engineers
+`,
+			expected: []string{
+				"update orgunit /Engineering",
+			},
+		},
+		{
+			name: "delete children before their parents",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+  - name: Sales
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Sales
+`,
+			expected: []string{
+				"delete orgunit /Engineering/Platform",
+				"delete orgunit /Engineering",
+			},
+		},
+		{
+			name: "keep protected org units",
+			live: `
+organization: example
+orgUnits:
+  - name: Legacy
+`,
+			config: `
+organization: example
+protected:
+  orgUnits: [/Legacy]
+`,
+			expected: nil,
+		},
+		{
+			name: "same name under different parents",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+  - name: Interns
+    parentOrgUnitPath: /Engineering
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+  - name: Interns
+    parentOrgUnitPath: /Engineering
+  - name: Interns
+    parentOrgUnitPath: /Sales
+`,
+			expected: []string{
+				"create orgunit /Sales/Interns",
+			},
+		},
+		{
+			name: "rename in place, moving children and users along",
+			live: `
+organization: example
+orgUnits:
+  - name: Eng
+  - name: Platform
+    parentOrgUnitPath: /Eng
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Eng/Platform
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+    previousPaths: [/Eng]
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Engineering/Platform
+`,
+			expected: []string{
+				"rename orgunit /Engineering",
+			},
+		},
+		{
+			name: "move to another parent",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+  - name: Interns
+    parentOrgUnitPath: /Sales
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+  - name: Interns
+    parentOrgUnitPath: /Engineering
+    previousPaths: [/Sales/Interns]
+`,
+			expected: []string{
+				"rename orgunit /Engineering/Interns",
+			},
+		},
+		{
+			name: "keep unconfigured parents outside the selection",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+`,
+			selection: &config.Selection{OrgUnitTrees: []string{"/Engineering/Platform"}},
+			expected: []string{
+				"create orgunit /Engineering/Platform",
+			},
+		},
+		{
+			name: "keep unconfigured protected parents",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+`,
+			config: `
+organization: example
+protected:
+  orgUnits: [/Engineering]
+orgUnits:
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+`,
+			expected: []string{
+				"create orgunit /Engineering/Platform",
+			},
+		},
+	})
+}
+
+func TestPlanOrgUnitsMissingParents(t *testing.T) {
+	testcases := []struct {
+		name string
+		live string
+	}{
+		{
+			name: "parent does not exist",
+			live: `
+organization: example
+`,
+		},
+		{
+			name: "parent is deleted",
+			live: `
+organization: example
+orgUnits:
+  - name: Engineering
+`,
+		},
+	}
+
+	cfg := `
+organization: example
+orgUnits:
+  - name: Platform
+    parentOrgUnitPath: /Engineering
+`
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+			syncConfig(t, clients, loadConfig(t, tc.live))
+
+			err := PlanOrgUnits(context.Background(), NewPlan(testOrganization), clients.directory, loadConfig(t, cfg), nil)
+			if err == nil || !strings.Contains(err.Error(), "neither exists nor is configured") {
+				t.Fatalf("Expected the missing parent to be reported, but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+type ResourceKind string
+
+const (
+	OrgUnitResource ResourceKind = "orgunit"
+	SchemaResource  ResourceKind = "schema"
+	UserResource    ResourceKind = "user"
+	AliasResource   ResourceKind = "alias"
+	LicenseResource ResourceKind = "license"
+	GroupResource   ResourceKind = "group"
+	MemberResource  ResourceKind = "member"
+
+	// GroupAliasResource is an alias of a group, AliasResource is only
+	// used for aliases of users.
+	GroupAliasResource ResourceKind = "groupalias"
+)
+
+type Operation string
+
+const (
+	CreateOperation Operation = "create"
+	UpdateOperation Operation = "update"
+	DeleteOperation Operation = "delete"
+
+	// SuspendOperation and MoveOperation are used instead of deletions
+	// for users, depending on the configured user removal policy.
+	SuspendOperation Operation = "suspend"
+	MoveOperation    Operation = "move"
+
+	// UnsuspendOperation reactivates a user that GMan suspended with a
+	// grace period and that is configured again.
+	UnsuspendOperation Operation = "unsuspend"
+
+	// RenameOperation updates a resource that is configured under a new
+	// name, but still exists under one of its previous names.
+	RenameOperation Operation = "rename"
+)
+
+// Plan is the ordered list of mutations that are required to bring the
+// organization in line with the configuration. Plans are created by the
+// Plan* functions and executed by ApplyPlan, possibly after having been
+// saved to and loaded from disk in between.
+type Plan struct {
+	Organization string   `json:"organization"`
+	Actions      []Action `json:"actions"`
+
+	// LiveCounts is the number of live org units, users and groups at
+	// the time of planning, used to check the deletion thresholds.
+	LiveCounts map[ResourceKind]int `json:"liveCounts,omitempty"`
+
+	logger actionLogger
+}
+
+// Action is a single mutation of a resource.
+type Action struct {
+	Resource  ResourceKind `json:"resource"`
+	Operation Operation    `json:"operation"`
+
+	// Name identifies the resource: the org unit name, the user's or
+	// group's email, the alias, the license name or the member's email.
+	Name string `json:"name"`
+
+	// Parent is the email of the user or group that an alias, license
+	// or member belongs to.
+	Parent string `json:"parent,omitempty"`
+
+	// ID and ParentID are the GSuite IDs of existing resources, needed
+	// by those API calls that do not accept names or emails.
+	ID       string `json:"id,omitempty"`
+	ParentID string `json:"parentId,omitempty"`
+
+	// Before is the live state and not set for create operations,
+	// After is the desired state and not set for delete operations.
+	Before *Value `json:"before,omitempty"`
+	After  *Value `json:"after,omitempty"`
+
+	// DeleteAfter is the date (YYYY-MM-DD) after which a suspended user
+	// is deleted; only set for suspensions with a grace period.
+	DeleteAfter string `json:"deleteAfter,omitempty"`
+
+	// TransferTo is the email of the user who receives the Drive and
+	// Calendar data of a deleted user, TransferToID is their GSuite ID.
+	TransferTo   string `json:"transferTo,omitempty"`
+	TransferToID string `json:"transferToId,omitempty"`
+
+	// OldName is the live name of a renamed resource; only set for
+	// rename operations.
+	OldName string `json:"oldName,omitempty"`
+}
+
+// Value holds the state of a resource; depending on the action's
+// resource kind, exactly one of the fields is set. Aliases and licenses
+// are fully described by the action's name and have no value.
+type Value struct {
+	OrgUnit *config.OrgUnit     `json:"orgUnit,omitempty"`
+	Schema  *directoryv1.Schema `json:"schema,omitempty"`
+	User    *config.User        `json:"user,omitempty"`
+	Group   *config.Group       `json:"group,omitempty"`
+	Member  *config.Member      `json:"member,omitempty"`
+}
+
+func NewPlan(organization string) *Plan {
+	return &Plan{
+		Organization: organization,
+		Actions:      []Action{},
+		LiveCounts:   map[ResourceKind]int{},
+	}
+}
+
+// Empty returns true if the plan contains no actions.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// TransfersData returns true if the plan transfers the data of deleted
+// users to other users.
+func (p *Plan) TransfersData() bool {
+	for _, action := range p.Actions {
+		if action.TransferTo != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add appends an action to the plan and logs it.
+func (p *Plan) add(action Action) {
+	p.logger.log(action)
+	p.Actions = append(p.Actions, action)
+}
+
+// renames returns the new names of all resources of the given kind that
+// are renamed by the plan, keyed by their old names.
+func (p *Plan) renames(resource ResourceKind) map[string]string {
+	renames := map[string]string{}
+
+	for _, action := range p.Actions {
+		if action.Resource == resource && action.Operation == RenameOperation {
+			renames[action.OldName] = action.Name
+		}
+	}
+
+	return renames
+}
+
+// upToDate logs a resource that requires no changes.
+func (p *Plan) upToDate(name string) {
+	log.Printf("  ✓ %s", name)
+	p.logger.parent = ""
+}
+
+// skipped logs a resource that is missing from the configuration, but
+// was already handled according to the user removal policy.
+func (p *Plan) skipped(name string, reason string) {
+	log.Printf("  ✓ %s (%s)", name, reason)
+	p.logger.parent = ""
+}
+
+// ignored logs a live resource that is not managed by GMan.
+func (p *Plan) ignored(name string) {
+	log.Printf("  ⊘ %s (ignored)", name)
+	p.logger.parent = ""
+}
+
+// protected logs a resource that is missing from the configuration,
+// but must not be deleted.
+func (p *Plan) protected(name string) {
+	p.notDeleted(name, "is protected")
+}
+
+// notDeleted logs a resource that is missing from the configuration,
+// but cannot be deleted for the given reason.
+func (p *Plan) notDeleted(name string, reason string) {
+	log.Printf("  ⚠ %s %s, not deleting it", name, reason)
+	p.logger.parent = ""
+}
+
+// Log prints all actions in the plan.
+func (p *Plan) Log() {
+	logger := actionLogger{}
+	for _, action := range p.Actions {
+		logger.log(action)
+	}
+}
+
+var operationSymbols = map[Operation]string{
+	CreateOperation:    "+",
+	UpdateOperation:    "✎",
+	DeleteOperation:    "-",
+	SuspendOperation:   "-",
+	MoveOperation:      "-",
+	UnsuspendOperation: "+",
+	RenameOperation:    "↻",
+}
+
+// actionLogger prints actions, nested below the user or group they
+// belong to. If a user or group itself is unchanged, a header line is
+// printed before its first nested action. Updates are followed by the
+// changed fields.
+type actionLogger struct {
+	parent string
+}
+
+func (l *actionLogger) log(action Action) {
+	symbol := operationSymbols[action.Operation]
+	indent := "  "
+
+	name := action.Name
+	if action.OldName != "" {
+		name = fmt.Sprintf("%s → %s", action.OldName, action.Name)
+	}
+
+	switch action.Resource {
+	case AliasResource, GroupAliasResource, LicenseResource, MemberResource:
+		if l.parent != action.Parent {
+			log.Printf("  %s %s", operationSymbols[UpdateOperation], action.Parent)
+			l.parent = action.Parent
+		}
+
+		indent = "    "
+
+		switch action.Resource {
+		case MemberResource:
+			log.Printf("%s%s %s", indent, symbol, action.Name)
+		case GroupAliasResource:
+			log.Printf("%s%s alias %s", indent, symbol, action.Name)
+		default:
+			log.Printf("%s%s %s %s", indent, symbol, action.Resource, action.Name)
+		}
+
+	case SchemaResource:
+		log.Printf("%s%s schema %s", indent, symbol, action.Name)
+		l.parent = ""
+
+	case UserResource:
+		if removal := userRemovalDescription(action); removal != "" {
+			log.Printf("%s%s %s (%s)", indent, symbol, name, removal)
+		} else {
+			log.Printf("%s%s %s", indent, symbol, name)
+		}
+		l.parent = action.Name
+
+	default:
+		log.Printf("%s%s %s", indent, symbol, name)
+		l.parent = action.Name
+	}
+
+	for _, diff := range action.Diff() {
+		log.Printf("%s    %s", indent, diff)
+	}
+}
+
+// userRemovalDescription returns what happens to a user that is removed
+// from or added back to the configuration, or an empty string for other
+// actions.
+func userRemovalDescription(action Action) string {
+	switch action.Operation {
+	case DeleteOperation:
+		if action.TransferTo != "" {
+			return fmt.Sprintf("delete, transfer data to %s", action.TransferTo)
+		}
+
+		return "delete"
+
+	case SuspendOperation:
+		if action.DeleteAfter != "" {
+			return fmt.Sprintf("suspend, delete after %s", action.DeleteAfter)
+		}
+
+		return "suspend"
+
+	case MoveOperation:
+		if action.After != nil && action.After.User != nil {
+			return fmt.Sprintf("move to %s", action.After.User.OrgUnitPath)
+		}
+
+		return "move"
+
+	case UnsuspendOperation:
+		return "unsuspend"
+
+	default:
+		return ""
+	}
+}
+
+func LoadPlanFromFile(filename string) (*Plan, error) {
+	plan := &Plan{}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func SavePlanToFile(plan *Plan, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(plan)
+}
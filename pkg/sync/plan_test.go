@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+func TestSavedPlans(t *testing.T) {
+	clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+
+	cfg := loadConfig(t, `
+organization: example
+orgUnits:
+  - name: Engineering
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Engineering
+    aliases: [jd@example.com]
+    licenses: [GoogleWorkspaceBusinessStarter]
+    password: i-am-not-secure-at-all
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: jane@example.com
+`)
+
+	plan := planConfig(t, clients, cfg)
+
+	filename := filepath.Join(t.TempDir(), "plan.json")
+	if err := SavePlanToFile(plan, filename); err != nil {
+		t.Fatalf("Failed to save plan: %v", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read plan: %v", err)
+	}
+
+	if strings.Contains(string(content), "i-am-not-secure-at-all") {
+		t.Fatalf("Saved plan contains the password: %s", content)
+	}
+
+	loaded, err := LoadPlanFromFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+
+	if !reflect.DeepEqual(describeActions(loaded), describeActions(plan)) {
+		t.Fatalf("Expected loaded actions\n%q\nbut got\n%q", describeActions(plan), describeActions(loaded))
+	}
+
+	applyPlan(t, clients, loaded)
+
+	if plan := planConfig(t, clients, cfg); !plan.Empty() {
+		t.Fatalf("Expected no actions after applying the loaded plan, but got %q", describeActions(plan))
+	}
+}
@@ -27,17 +27,15 @@ import (
 	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func SyncSchema(
+// PlanSchema adds the actions required to create or update GMan's custom
+// user schema to the plan.
+func PlanSchema(
 	ctx context.Context,
-	directorySrv *glib.DirectoryService,
-	confirm bool,
+	plan *Plan,
+	directorySrv glib.DirectoryClient,
 ) error {
 	log.Println("⇄ Syncing schema…")
 
-	if !confirm {
-		return nil
-	}
-
 	desiredSchema := &directoryv1.Schema{
 		DisplayName: "GMan",
 		SchemaName:  config.SchemaName,
@@ -48,16 +46,40 @@ func SyncSchema(
 				ReadAccessType: "ADMINS_AND_SELF",
 				Indexed:        googleapi.Bool(false),
 			},
+			{
+				FieldName:      config.DeleteAfterCustomField,
+				FieldType:      "DATE",
+				ReadAccessType: "ADMINS_AND_SELF",
+				Indexed:        googleapi.Bool(false),
+			},
 		},
 	}
 
 	schema, err := directorySrv.GetSchema(ctx, config.SchemaName)
-
 	if err != nil {
-		_, err = directorySrv.CreateSchema(ctx, desiredSchema)
-	} else {
-		_, err = directorySrv.UpdateSchema(ctx, schema, desiredSchema)
+		plan.add(Action{
+			Resource:  SchemaResource,
+			Operation: CreateOperation,
+			Name:      config.SchemaName,
+			After:     &Value{Schema: desiredSchema},
+		})
+
+		return nil
 	}
 
-	return err
+	if schemaUpToDate(desiredSchema, schema) {
+		plan.upToDate("schema " + config.SchemaName)
+		return nil
+	}
+
+	plan.add(Action{
+		Resource:  SchemaResource,
+		Operation: UpdateOperation,
+		Name:      config.SchemaName,
+		ID:        schema.SchemaId,
+		Before:    &Value{Schema: schema},
+		After:     &Value{Schema: desiredSchema},
+	})
+
+	return nil
 }
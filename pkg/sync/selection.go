@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+// The select* functions restrict the live and configured resources to the
+// selection. Resources are selected if either their live or their
+// configured version is, so that e.g. a user moving out of a selected org
+// unit is updated rather than deleted. Likewise, org units are selected by
+// both their live path and the path they are renamed or moved to.
+
+func selectOrgUnits(selection *config.Selection, live []*directoryv1.OrgUnit, configured []config.OrgUnit) ([]*directoryv1.OrgUnit, []config.OrgUnit) {
+	if selection.Empty() {
+		return live, configured
+	}
+
+	renames := orgUnitRenames(live, configured)
+
+	// the paths of the selected live org units after all renames
+	paths := sets.NewString()
+
+	selectedLive := []*directoryv1.OrgUnit{}
+	for _, orgUnit := range live {
+		orgUnitPath := renamedPath(orgUnit.OrgUnitPath, renames)
+
+		if selection.IncludesOrgUnit(orgUnit.OrgUnitPath) || selection.IncludesOrgUnit(orgUnitPath) {
+			selectedLive = append(selectedLive, orgUnit)
+			paths.Insert(orgUnitPath)
+		}
+	}
+
+	selectedConfigured := []config.OrgUnit{}
+	for _, orgUnit := range configured {
+		if selection.IncludesOrgUnit(orgUnit.OrgUnitPath()) || paths.Has(orgUnit.OrgUnitPath()) {
+			selectedConfigured = append(selectedConfigured, orgUnit)
+		}
+	}
+
+	return selectedLive, selectedConfigured
+}
+
+func selectUsers(selection *config.Selection, live []*directoryv1.User, configured []config.User, renames map[string]string) ([]*directoryv1.User, []config.User) {
+	if selection.Empty() {
+		return live, configured
+	}
+
+	emails := sets.NewString()
+	for _, user := range live {
+		if selection.IncludesUser(user.PrimaryEmail, user.OrgUnitPath) {
+			emails.Insert(user.PrimaryEmail)
+		}
+	}
+
+	for _, user := range configured {
+		if selection.IncludesUser(user.PrimaryEmail, user.OrgUnitPath) {
+			emails.Insert(user.PrimaryEmail)
+		}
+	}
+
+	// renamed users are selected by both their current and previous emails
+	for _, user := range configured {
+		userEmails := append([]string{user.PrimaryEmail}, user.PreviousEmails...)
+		if emails.HasAny(userEmails...) {
+			emails.Insert(userEmails...)
+		}
+	}
+
+	// the same goes for users renamed because of their employee ID
+	for oldEmail, newEmail := range renames {
+		if emails.HasAny(oldEmail, newEmail) {
+			emails.Insert(oldEmail, newEmail)
+		}
+	}
+
+	selectedLive := []*directoryv1.User{}
+	for _, user := range live {
+		if emails.Has(user.PrimaryEmail) {
+			selectedLive = append(selectedLive, user)
+		}
+	}
+
+	selectedConfigured := []config.User{}
+	for _, user := range configured {
+		if emails.Has(user.PrimaryEmail) {
+			selectedConfigured = append(selectedConfigured, user)
+		}
+	}
+
+	return selectedLive, selectedConfigured
+}
+
+func selectGroups(selection *config.Selection, live []*directoryv1.Group, configured []config.Group) ([]*directoryv1.Group, []config.Group) {
+	if selection.Empty() {
+		return live, configured
+	}
+
+	// renamed groups are selected by both their current and previous emails
+	emails := sets.NewString()
+	for _, group := range configured {
+		groupEmails := append([]string{group.Email}, group.PreviousEmails...)
+		for _, email := range groupEmails {
+			if selection.IncludesGroup(email) {
+				emails.Insert(groupEmails...)
+				break
+			}
+		}
+	}
+
+	selectedLive := []*directoryv1.Group{}
+	for _, group := range live {
+		if selection.IncludesGroup(group.Email) || emails.Has(group.Email) {
+			selectedLive = append(selectedLive, group)
+		}
+	}
+
+	selectedConfigured := []config.Group{}
+	for _, group := range configured {
+		if emails.Has(group.Email) {
+			selectedConfigured = append(selectedConfigured, group)
+		}
+	}
+
+	return selectedLive, selectedConfigured
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"testing"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+)
+
+func TestPlanSelection(t *testing.T) {
+	const live = `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Engineering
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    orgUnitPath: /Sales
+groups:
+  - name: Developers
+    email: developers@example.com
+  - name: Sellers
+    email: sellers@example.com
+`
+
+	runTestcases(t, []testcase{
+		{
+			name: "only selected users",
+			live: live,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+users:
+  - givenName: Jane
+    familyName: Smith
+    primaryEmail: jane@example.com
+    orgUnitPath: /Engineering
+`,
+			selection: &config.Selection{Users: []string{"jane@*"}},
+			expected: []string{
+				"update user jane@example.com",
+			},
+		},
+		{
+			name: "only selected groups",
+			live: live,
+			config: `
+organization: example
+`,
+			selection: &config.Selection{Groups: []string{"sellers@example.com"}},
+			expected: []string{
+				"delete group sellers@example.com",
+			},
+		},
+		{
+			name: "only users in the selected org unit subtree",
+			live: live,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+`,
+			selection: &config.Selection{OrgUnitTrees: []string{"/Engineering"}},
+			expected: []string{
+				"delete user jane@example.com",
+			},
+		},
+		{
+			name: "update users moving out of the selected org unit subtree",
+			live: live,
+			config: `
+organization: example
+orgUnits:
+  - name: Engineering
+  - name: Sales
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    orgUnitPath: /Sales
+`,
+			selection: &config.Selection{OrgUnitTrees: []string{"/Engineering"}},
+			expected: []string{
+				"update user jane@example.com",
+			},
+		},
+		{
+			name: "rename org units moving into the selected subtree",
+			live: `
+organization: example
+orgUnits:
+  - name: Old
+  - name: Platform
+    parentOrgUnitPath: /Old
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: New
+    previousPaths: [/Old]
+  - name: Platform
+    parentOrgUnitPath: /New
+`,
+			selection: &config.Selection{OrgUnitTrees: []string{"/New"}},
+			expected: []string{
+				"rename orgunit /New",
+			},
+		},
+		{
+			name: "rename org units moving out of the selected subtree",
+			live: `
+organization: example
+orgUnits:
+  - name: Old
+  - name: Platform
+    parentOrgUnitPath: /Old
+`,
+			config: `
+organization: example
+orgUnits:
+  - name: New
+    previousPaths: [/Old]
+  - name: Platform
+    parentOrgUnitPath: /New
+`,
+			selection: &config.Selection{OrgUnitTrees: []string{"/Old"}},
+			expected: []string{
+				"rename orgunit /New",
+			},
+		},
+		{
+			name: "rename selected users via employee ID",
+			live: `
+organization: example
+users:
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    employeeInfo:
+      id: "42"
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			config: `
+organization: example
+users:
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: robert@example.com
+    employeeInfo:
+      id: "42"
+`,
+			selection: &config.Selection{Users: []string{"robert@example.com"}},
+			expected: []string{
+				"rename user robert@example.com",
+				"delete alias robert@example.com/bob@example.com",
+			},
+		},
+	})
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+const testOrganization = "example"
+
+func TestMain(m *testing.M) {
+	// planning and applying logs every resource
+	log.SetOutput(io.Discard)
+
+	// the fake completes data transfers when they are first checked
+	transferPollInterval = time.Millisecond
+
+	os.Exit(m.Run())
+}
+
+// testClients are the API clients that a test syncs with.
+type testClients struct {
+	directory      glib.DirectoryClient
+	licensing      glib.LicensingClient
+	groupsSettings glib.GroupsSettingsClient
+	dataTransfer   glib.DataTransferClient
+}
+
+func fakeClients(ws *fake.Workspace) testClients {
+	return testClients{
+		directory:      ws,
+		licensing:      ws,
+		groupsSettings: ws,
+		dataTransfer:   ws,
+	}
+}
+
+// testcase describes a sync from the live state, which is created by
+// syncing the live configuration first, to the configuration. An optional
+// selection restricts the sync like the -only-* flags do.
+type testcase struct {
+	name      string
+	live      string
+	config    string
+	selection *config.Selection
+	expected  []string
+}
+
+// runTestcases plans every testcase against a new fake workspace and
+// compares the planned actions. Afterwards, the plan is applied and a
+// second plan must be empty.
+func runTestcases(t *testing.T, testcases []testcase) {
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+
+			if tc.live != "" {
+				syncConfig(t, clients, loadConfig(t, tc.live))
+			}
+
+			cfg := loadConfig(t, tc.config)
+
+			plan := planSelection(t, clients, cfg, tc.selection)
+			if actions := describeActions(plan); !reflect.DeepEqual(actions, tc.expected) {
+				t.Fatalf("Expected actions\n%q\nbut got\n%q", tc.expected, actions)
+			}
+
+			applyPlan(t, clients, plan)
+
+			if plan := planSelection(t, clients, cfg, tc.selection); !plan.Empty() {
+				t.Fatalf("Expected no actions after applying the plan, but got %q", describeActions(plan))
+			}
+		})
+	}
+}
+
+// loadConfig loads a configuration like the command line does, so that
+// all defaults are applied.
+func loadConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.LoadFromFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	return cfg
+}
+
+// planConfig plans all resources, using the same configuration for org
+// units, users and groups.
+func planConfig(t *testing.T, clients testClients, cfg *config.Config) *Plan {
+	t.Helper()
+
+	return planSelection(t, clients, cfg, nil)
+}
+
+// planSelection plans the selected resources, skipping the kinds of
+// resources that are not part of the selection like the command line does.
+func planSelection(t *testing.T, clients testClients, cfg *config.Config, selection *config.Selection) *Plan {
+	t.Helper()
+
+	ctx := context.Background()
+	plan := NewPlan(cfg.Organization)
+
+	licenseStatus, err := glib.GetLicenseStatus(ctx, clients.licensing)
+	if err != nil {
+		t.Fatalf("Failed to fetch license status: %v", err)
+	}
+
+	if selection.SelectsOrgUnits() {
+		if err := PlanOrgUnits(ctx, plan, clients.directory, cfg, selection); err != nil {
+			t.Fatalf("Failed to plan org units: %v", err)
+		}
+	}
+
+	if selection.SelectsUsers() {
+		if err := PlanSchema(ctx, plan, clients.directory); err != nil {
+			t.Fatalf("Failed to plan schema: %v", err)
+		}
+
+		if err := PlanUsers(ctx, plan, clients.directory, cfg, selection, licenseStatus, true); err != nil {
+			t.Fatalf("Failed to plan users: %v", err)
+		}
+	}
+
+	if selection.SelectsGroups() {
+		if err := PlanGroups(ctx, plan, clients.directory, clients.groupsSettings, cfg, selection, 2); err != nil {
+			t.Fatalf("Failed to plan groups: %v", err)
+		}
+	}
+
+	return plan
+}
+
+func applyPlan(t *testing.T, clients testClients, plan *Plan) {
+	t.Helper()
+
+	if err := ApplyPlan(context.Background(), plan, clients.directory, clients.licensing, clients.groupsSettings, clients.dataTransfer); err != nil {
+		t.Fatalf("Failed to apply plan: %v", err)
+	}
+}
+
+func syncConfig(t *testing.T, clients testClients, cfg *config.Config) {
+	t.Helper()
+
+	applyPlan(t, clients, planConfig(t, clients, cfg))
+}
+
+// describeActions returns the actions as "operation resource name"
+// strings, with the name prefixed by the parent for aliases, licenses
+// and members. Schema actions are left out, as every first sync has one.
+func describeActions(plan *Plan) []string {
+	var actions []string
+
+	for _, action := range plan.Actions {
+		if action.Resource == SchemaResource {
+			continue
+		}
+
+		name := action.Name
+		if action.Parent != "" {
+			name = action.Parent + "/" + name
+		}
+
+		actions = append(actions, fmt.Sprintf("%s %s %s", action.Operation, action.Resource, name))
+	}
+
+	return actions
+}
@@ -21,161 +21,438 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"time"
 
 	directoryv1 "google.golang.org/api/admin/directory/v1"
+
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubermatic-labs/gman/pkg/config"
 	"github.com/kubermatic-labs/gman/pkg/glib"
 )
 
-func SyncUsers(
+// deleteAfterLayout is the date format used in the gman custom schema.
+const deleteAfterLayout = "2006-01-02"
+
+// PlanUsers adds the actions required to sync users, their aliases and
+// licenses to the plan. Passwords are only included in the plan if
+// insecure passwords are enabled.
+func PlanUsers(
 	ctx context.Context,
-	directorySrv *glib.DirectoryService,
-	licensingSrv *glib.LicensingService,
+	plan *Plan,
+	directorySrv glib.DirectoryClient,
 	cfg *config.Config,
+	selection *config.Selection,
 	licenseStatus *glib.LicenseStatus,
 	enableInsecurePasswords bool,
-	confirm bool,
-) (bool, error) {
-	changes := false
-
+) error {
 	log.Println("⇄ Syncing users…")
 
 	liveUsers, err := directorySrv.ListUsers(ctx)
 	if err != nil {
-		return changes, err
+		return err
 	}
 
-	liveEmails := sets.NewString()
+	canonicalizeUsers(liveUsers)
+
+	// ignored users can still receive data transfers
+	liveUsersByEmail := map[string]*directoryv1.User{}
+	for _, liveUser := range liveUsers {
+		liveUsersByEmail[liveUser.PrimaryEmail] = liveUser
+	}
 
 	sort.Slice(liveUsers, func(i, j int) bool {
 		return liveUsers[i].PrimaryEmail < liveUsers[j].PrimaryEmail
 	})
 
+	liveUsers = withoutIgnoredUsers(plan, &cfg.Ignore, liveUsers)
+	plan.LiveCounts[UserResource] = len(liveUsers)
+
+	// renames are determined before selecting users, so that a selection
+	// always includes both the old and new email of a renamed user
+	renames, err := userRenames(liveUsers, cfg.Users)
+	if err != nil {
+		return err
+	}
+
+	liveUsers, expectedUsers := selectUsers(selection, liveUsers, cfg.Users, renames)
+
+	// users move along with renamed org units, so refer to those by
+	// their new path
+	renamedOrgUnits := plan.renames(OrgUnitResource)
+
+	liveEmails := sets.NewString()
+
 	for _, liveUser := range liveUsers {
-		liveEmails.Insert(liveUser.PrimaryEmail)
+		// the primary email after a possible rename
+		email := liveUser.PrimaryEmail
+		if newEmail, ok := renames[email]; ok {
+			email = newEmail
+		}
+
+		liveEmails.Insert(email)
 
 		found := false
 
-		for _, expectedUser := range cfg.Users {
-			if expectedUser.PrimaryEmail == liveUser.PrimaryEmail {
+		for _, expectedUser := range expectedUsers {
+			if expectedUser.PrimaryEmail == email {
 				found = true
 
+				if !enableInsecurePasswords {
+					expectedUser.Password = ""
+				}
+
 				currentUserLicenses := licenseStatus.GetLicensesForUser(liveUser)
 
 				currentAliases, err := directorySrv.GetUserAliases(ctx, liveUser)
 				if err != nil {
-					return changes, fmt.Errorf("failed to fetch aliases: %v", err)
+					return fmt.Errorf("failed to fetch aliases: %v", err)
+				}
+
+				currentUser, err := config.ToConfigUser(liveUser, currentUserLicenses)
+				if err != nil {
+					return fmt.Errorf("failed to convert user %s: %v", liveUser.PrimaryEmail, err)
+				}
+
+				currentUser.OrgUnitPath = renamedPath(currentUser.OrgUnitPath, renamedOrgUnits)
+
+				renamed := email != liveUser.PrimaryEmail
+				if renamed {
+					// GSuite keeps the old primary email as an alias, which
+					// is removed unless it is configured as an alias
+					currentAliases = append(currentAliases, liveUser.PrimaryEmail)
 				}
 
-				infoUpToDate := userUpToDate(expectedUser, liveUser, currentUserLicenses, currentAliases)
-				passwordUpToDate := !enableInsecurePasswords || passwordUpToDate(expectedUser, liveUser)
+				infoUpToDate := userUpToDate(expectedUser, currentUser)
+				passwordUpToDate := passwordUpToDate(expectedUser, liveUser)
+				aliasesUpToDate := sets.NewString(expectedUser.Aliases...).Equal(sets.NewString(currentAliases...))
+				licensesUpToDate := sets.NewString(expectedUser.Licenses...).Equal(sets.NewString(currentUser.Licenses...))
 
-				if infoUpToDate && passwordUpToDate {
+				// users that GMan suspended with a grace period are
+				// reactivated once they are configured again
+				liveSchema := config.GetUserSchema(liveUser)
+				unsuspend := liveUser.Suspended && liveSchema != nil && liveSchema.DeleteAfter != ""
+
+				if infoUpToDate && passwordUpToDate && aliasesUpToDate && licensesUpToDate && !unsuspend {
 					// no update needed
-					log.Printf("  ✓ %s", expectedUser.PrimaryEmail)
-				} else {
-					// update it
-					changes = true
-					log.Printf("  ✎ %s", expectedUser.PrimaryEmail)
-
-					updatedUser := liveUser
-					if confirm {
-						apiUser := config.ToGSuiteUser(&expectedUser, enableInsecurePasswords)
-						updatedUser, err = directorySrv.UpdateUser(ctx, liveUser, apiUser)
-						if err != nil {
-							return changes, fmt.Errorf("failed to update user: %v", err)
+					plan.upToDate(expectedUser.PrimaryEmail)
+					break
+				}
+
+				// update it
+				if !infoUpToDate || !passwordUpToDate {
+					before := userAttributes(currentUser)
+					after := userAttributes(expectedUser)
+
+					// only include the password if it needs to be changed
+					if !passwordUpToDate {
+						if err := hashPassword(&after, expectedUser.Password); err != nil {
+							return fmt.Errorf("failed to hash password of %s: %v", expectedUser.PrimaryEmail, err)
 						}
 					}
 
-					if err := syncUserAliases(ctx, directorySrv, &expectedUser, updatedUser, currentAliases, confirm); err != nil {
-						return changes, fmt.Errorf("failed to sync aliases: %v", err)
+					action := Action{
+						Resource:  UserResource,
+						Operation: UpdateOperation,
+						Name:      expectedUser.PrimaryEmail,
+						ID:        liveUser.Id,
+						Before:    &Value{User: &before},
+						After:     &Value{User: &after},
 					}
 
-					if err := syncUserLicenses(ctx, licensingSrv, &expectedUser, updatedUser, licenseStatus, confirm); err != nil {
-						return changes, fmt.Errorf("failed to sync licenses: %v", err)
+					if renamed {
+						action.Operation = RenameOperation
+						action.OldName = liveUser.PrimaryEmail
 					}
+
+					plan.add(action)
+				}
+
+				if unsuspend {
+					plan.add(Action{
+						Resource:  UserResource,
+						Operation: UnsuspendOperation,
+						Name:      expectedUser.PrimaryEmail,
+						ID:        liveUser.Id,
+					})
 				}
 
+				planUserAliases(plan, &expectedUser, currentAliases)
+				planUserLicenses(plan, &expectedUser, currentUser.Licenses)
+
 				break
 			}
 		}
 
 		if !found {
-			changes = true
-			log.Printf("  - %s", liveUser.PrimaryEmail)
+			if cfg.Protected.IsUserProtected(liveUser.PrimaryEmail) {
+				plan.protected(liveUser.PrimaryEmail)
+				continue
+			}
 
-			if confirm {
-				if err := directorySrv.DeleteUser(ctx, liveUser); err != nil {
-					return changes, fmt.Errorf("failed to delete user: %v", err)
-				}
+			if err := planUserRemoval(plan, cfg, liveUser, liveUsersByEmail, licenseStatus); err != nil {
+				return err
 			}
 		}
 	}
 
-	for _, expectedUser := range cfg.Users {
+	for _, expectedUser := range expectedUsers {
 		if !liveEmails.Has(expectedUser.PrimaryEmail) {
-			changes = true
-			log.Printf("  + %s", expectedUser.PrimaryEmail)
-
-			var createdUser *directoryv1.User
+			if !enableInsecurePasswords {
+				expectedUser.Password = ""
+			}
 
-			if confirm {
-				apiUser := config.ToGSuiteUser(&expectedUser, enableInsecurePasswords)
-				createdUser, err = directorySrv.CreateUser(ctx, apiUser)
-				if err != nil {
-					return changes, fmt.Errorf("failed to create user: %v", err)
-				}
+			after := userAttributes(expectedUser)
+			if err := hashPassword(&after, expectedUser.Password); err != nil {
+				return fmt.Errorf("failed to hash password of %s: %v", expectedUser.PrimaryEmail, err)
 			}
 
-			if err := syncUserAliases(ctx, directorySrv, &expectedUser, createdUser, nil, confirm); err != nil {
-				return changes, fmt.Errorf("failed to sync aliases: %v", err)
+			plan.add(Action{
+				Resource:  UserResource,
+				Operation: CreateOperation,
+				Name:      expectedUser.PrimaryEmail,
+				After:     &Value{User: &after},
+			})
+
+			planUserAliases(plan, &expectedUser, nil)
+			planUserLicenses(plan, &expectedUser, nil)
+		}
+	}
+
+	return nil
+}
+
+// userRenames returns the new primary emails of all live users that are
+// configured under a different email, keyed by their live email. Users
+// are matched by their previous emails first and by their employee ID
+// second; employee IDs that are not unique are not considered.
+func userRenames(liveUsers []*directoryv1.User, expectedUsers []config.User) (map[string]string, error) {
+	expectedEmails := sets.NewString()
+	for _, expectedUser := range expectedUsers {
+		expectedEmails.Insert(expectedUser.PrimaryEmail)
+	}
+
+	liveEmails := sets.NewString()
+	liveEmailsByEmployeeID := map[string][]string{}
+
+	for _, liveUser := range liveUsers {
+		liveEmails.Insert(liveUser.PrimaryEmail)
+
+		// configured users are never renamed
+		if expectedEmails.Has(liveUser.PrimaryEmail) {
+			continue
+		}
+
+		currentUser, err := config.ToConfigUser(liveUser, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert user %s: %v", liveUser.PrimaryEmail, err)
+		}
+
+		if id := currentUser.Employee.EmployeeID; id != "" {
+			liveEmailsByEmployeeID[id] = append(liveEmailsByEmployeeID[id], liveUser.PrimaryEmail)
+		}
+	}
+
+	renames := map[string]string{}
+	renamed := sets.NewString()
+
+	for _, expectedUser := range expectedUsers {
+		if liveEmails.Has(expectedUser.PrimaryEmail) {
+			continue
+		}
+
+		candidates := []string{}
+		for _, previousEmail := range expectedUser.PreviousEmails {
+			if liveEmails.Has(previousEmail) && !expectedEmails.Has(previousEmail) {
+				candidates = append(candidates, previousEmail)
 			}
+		}
 
-			if err := syncUserLicenses(ctx, licensingSrv, &expectedUser, createdUser, licenseStatus, confirm); err != nil {
-				return changes, fmt.Errorf("failed to sync licenses: %v", err)
+		if emails := liveEmailsByEmployeeID[expectedUser.Employee.EmployeeID]; expectedUser.Employee.EmployeeID != "" && len(emails) == 1 {
+			candidates = append(candidates, emails[0])
+		}
+
+		for _, candidate := range candidates {
+			if !renamed.Has(candidate) {
+				renames[candidate] = expectedUser.PrimaryEmail
+				renamed.Insert(candidate)
+				break
 			}
 		}
 	}
 
-	return changes, nil
+	return renames, nil
 }
 
-func syncUserAliases(
-	ctx context.Context,
-	directorySrv *glib.DirectoryService,
-	expectedUser *config.User,
+// planUserRemoval adds the actions for a user that is not configured
+// anymore, according to the user removal policy.
+func planUserRemoval(
+	plan *Plan,
+	cfg *config.Config,
 	liveUser *directoryv1.User,
-	liveAliases []string,
-	confirm bool,
+	liveUsers map[string]*directoryv1.User,
+	licenseStatus *glib.LicenseStatus,
 ) error {
-	expectedAliases := sets.NewString(expectedUser.Aliases...)
-	liveAliasesSet := sets.NewString(liveAliases...)
+	policy := cfg.UserRemovalPolicy
 
-	for _, liveAlias := range liveAliases {
-		if !expectedAliases.Has(liveAlias) {
-			log.Printf("    - alias %s", liveAlias)
+	currentUser, err := config.ToConfigUser(liveUser, licenseStatus.GetLicensesForUser(liveUser))
+	if err != nil {
+		return fmt.Errorf("failed to convert user %s: %v", liveUser.PrimaryEmail, err)
+	}
+
+	before := userAttributes(currentUser)
+	action := Action{
+		Resource:  UserResource,
+		Operation: DeleteOperation,
+		Name:      liveUser.PrimaryEmail,
+		ID:        liveUser.Id,
+		Before:    &Value{User: &before},
+	}
+
+	switch policy.Policy {
+	case config.UserRemovalSuspend:
+		if liveUser.Suspended {
+			plan.skipped(liveUser.PrimaryEmail, "suspended")
+			return nil
+		}
 
-			if confirm {
-				if err := directorySrv.DeleteUserAlias(ctx, liveUser, liveAlias); err != nil {
-					return fmt.Errorf("unable to delete alias: %v", err)
+		action.Operation = SuspendOperation
+
+	case config.UserRemovalSuspendThenDelete:
+		if liveUser.Suspended {
+			deleteAfter := ""
+			if schema := config.GetUserSchema(liveUser); schema != nil {
+				deleteAfter = schema.DeleteAfter
+			}
+
+			// users that were suspended by hand or before the grace
+			// period was configured start their grace period now
+			if date, err := time.Parse(deleteAfterLayout, deleteAfter); err == nil {
+				if time.Now().Before(date.AddDate(0, 0, 1)) {
+					plan.skipped(liveUser.PrimaryEmail, fmt.Sprintf("suspended, will be deleted after %s", deleteAfter))
+					return nil
 				}
+
+				// grace period is over
+				break
 			}
 		}
+
+		action.Operation = SuspendOperation
+		action.DeleteAfter = time.Now().Add(policy.GracePeriod).Format(deleteAfterLayout)
+
+	case config.UserRemovalMoveToOrgUnit:
+		if currentUser.OrgUnitPath == policy.OrgUnitPath && len(currentUser.Licenses) == 0 {
+			plan.skipped(liveUser.PrimaryEmail, fmt.Sprintf("moved to %s", policy.OrgUnitPath))
+			return nil
+		}
+
+		if currentUser.OrgUnitPath != policy.OrgUnitPath {
+			after := before
+			after.OrgUnitPath = policy.OrgUnitPath
+
+			action.Operation = MoveOperation
+			action.After = &Value{User: &after}
+
+			plan.add(action)
+		}
+
+		// strip all licenses, so offboarded users do not incur costs
+		planUserLicenses(plan, &config.User{PrimaryEmail: liveUser.PrimaryEmail}, currentUser.Licenses)
+
+		return nil
 	}
 
-	for _, expectedAlias := range expectedAliases.List() {
-		if !liveAliasesSet.Has(expectedAlias) {
-			log.Printf("    + alias %s", expectedAlias)
+	if action.Operation == DeleteOperation && cfg.DataTransfer != nil {
+		recipient := dataTransferRecipient(cfg, &before, liveUsers)
+		if recipient == nil {
+			plan.notDeleted(liveUser.PrimaryEmail, "has no recipient for their data")
+			return nil
+		}
 
-			if confirm {
-				if err := directorySrv.CreateUserAlias(ctx, liveUser, expectedAlias); err != nil {
-					return fmt.Errorf("unable to create alias: %v", err)
-				}
-			}
+		action.TransferTo = recipient.PrimaryEmail
+		action.TransferToID = recipient.Id
+	}
+
+	plan.add(action)
+
+	return nil
+}
+
+// dataTransferRecipient returns the user's manager, or else the fallback
+// owner, if they remain in the organization after the sync.
+func dataTransferRecipient(cfg *config.Config, user *config.User, liveUsers map[string]*directoryv1.User) *directoryv1.User {
+	for _, email := range []string{user.Employee.ManagerEmail, cfg.DataTransfer.FallbackOwner} {
+		if email == "" || email == user.PrimaryEmail {
+			continue
 		}
+
+		recipient, exists := liveUsers[email]
+		if !exists || recipient.Suspended {
+			continue
+		}
+
+		if !cfg.Protected.IsUserProtected(email) && !cfg.Ignore.IsUserIgnored(email, recipient.OrgUnitPath) && !userConfigured(cfg, email) {
+			continue
+		}
+
+		return recipient
 	}
 
 	return nil
 }
+
+func userConfigured(cfg *config.Config, email string) bool {
+	for _, user := range cfg.Users {
+		if user.PrimaryEmail == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hashPassword sets the hash of the password on the user; plans only
+// ever contain the hash, never the password itself.
+func hashPassword(user *config.User, password string) error {
+	if password == "" {
+		return nil
+	}
+
+	hash, err := config.CryptPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	user.HashFunction = config.PasswordHashFunction
+
+	return nil
+}
+
+func planUserAliases(plan *Plan, expectedUser *config.User, liveAliases []string) {
+	expectedAliases := sets.NewString(expectedUser.Aliases...)
+	liveAliasesSet := sets.NewString(liveAliases...)
+
+	for _, liveAlias := range liveAliases {
+		if !expectedAliases.Has(liveAlias) {
+			plan.add(Action{
+				Resource:  AliasResource,
+				Operation: DeleteOperation,
+				Name:      liveAlias,
+				Parent:    expectedUser.PrimaryEmail,
+			})
+		}
+	}
+
+	for _, expectedAlias := range expectedAliases.List() {
+		if !liveAliasesSet.Has(expectedAlias) {
+			plan.add(Action{
+				Resource:  AliasResource,
+				Operation: CreateOperation,
+				Name:      expectedAlias,
+				Parent:    expectedUser.PrimaryEmail,
+			})
+		}
+	}
+}
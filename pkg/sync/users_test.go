@@ -0,0 +1,625 @@
+/*
+Copyright 2021 The Kubermatic Kubernetes Platform contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	directoryv1 "google.golang.org/api/admin/directory/v1"
+
+	"github.com/kubermatic-labs/gman/pkg/config"
+	"github.com/kubermatic-labs/gman/pkg/glib/fake"
+)
+
+func TestPlanUsers(t *testing.T) {
+	runTestcases(t, []testcase{
+		{
+			name: "create with aliases and licenses",
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    aliases: [jd@example.com]
+    licenses: [GoogleWorkspaceBusinessStarter]
+`,
+			expected: []string{
+				"create user jane@example.com",
+				"create alias jane@example.com/jd@example.com",
+				"create license jane@example.com/GoogleWorkspaceBusinessStarter",
+			},
+		},
+		{
+			name: "update attributes, aliases and licenses",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    aliases: [jd@example.com]
+    licenses: [GoogleWorkspaceBusinessStarter]
+`,
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Smith
+    primaryEmail: jane@example.com
+    aliases: [js@example.com]
+    licenses: [GoogleWorkspaceBusinessStandard]
+`,
+			expected: []string{
+				"update user jane@example.com",
+				"delete alias jane@example.com/jd@example.com",
+				"create alias jane@example.com/js@example.com",
+				"delete license jane@example.com/GoogleWorkspaceBusinessStarter",
+				"create license jane@example.com/GoogleWorkspaceBusinessStandard",
+			},
+		},
+		{
+			name: "delete unconfigured users",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+`,
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"delete user bob@example.com",
+			},
+		},
+		{
+			name: "keep protected users",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+`,
+			config: `
+organization: example
+protected:
+  users: ["b*@example.com"]
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: nil,
+		},
+		{
+			name: "match mixed-case emails",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    licenses: [GoogleWorkspaceBusinessStarter]
+`,
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: Jane@Example.com
+    licenses: [GoogleWorkspaceBusinessStarter]
+`,
+			expected: nil,
+		},
+		{
+			name: "rename via previous emails",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane.doe@example.com
+    previousEmails: [jane@example.com]
+`,
+			expected: []string{
+				"rename user jane.doe@example.com",
+				"delete alias jane.doe@example.com/jane@example.com",
+			},
+		},
+		{
+			name: "rename via employee ID",
+			live: `
+organization: example
+users:
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    employeeInfo:
+      id: "42"
+`,
+			config: `
+organization: example
+users:
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: robert@example.com
+    aliases: [bob@example.com]
+    employeeInfo:
+      id: "42"
+`,
+			expected: []string{
+				"rename user robert@example.com",
+			},
+		},
+		{
+			name: "rename group members along",
+			live: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+groups:
+  - name: Developers
+    email: developers@example.com
+    members:
+      - email: jane@example.com
+`,
+			config: `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane.doe@example.com
+    previousEmails: [jane@example.com]
+groups:
+  - name: Developers
+    email: developers@example.com
+    members:
+      - email: jane.doe@example.com
+`,
+			expected: []string{
+				"rename user jane.doe@example.com",
+				"delete alias jane.doe@example.com/jane@example.com",
+			},
+		},
+	})
+}
+
+func TestPlanUsersPasswords(t *testing.T) {
+	const password = "i-am-not-secure-at-all"
+
+	clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+
+	cfg := loadConfig(t, `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+    password: `+password+`
+`)
+
+	plan := planConfig(t, clients, cfg)
+
+	encoded, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to encode plan: %v", err)
+	}
+
+	if strings.Contains(string(encoded), password) {
+		t.Fatalf("Plan contains the password: %s", encoded)
+	}
+
+	applyPlan(t, clients, plan)
+
+	if actions := describeActions(planConfig(t, clients, cfg)); len(actions) > 0 {
+		t.Fatalf("Expected no actions for an unchanged password, but got %q", actions)
+	}
+
+	cfg.Users[0].Password = "something-else"
+
+	expected := []string{"update user jane@example.com"}
+	if actions := describeActions(planConfig(t, clients, cfg)); !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("Expected actions\n%q\nbut got\n%q", expected, actions)
+	}
+}
+
+func TestPlanUserRemoval(t *testing.T) {
+	const live = `
+organization: example
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    licenses: [GoogleWorkspaceBusinessStarter]
+`
+
+	runTestcases(t, []testcase{
+		{
+			name: "delete",
+			live: live,
+			config: `
+organization: example
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"delete user bob@example.com",
+			},
+		},
+		{
+			name: "delete after transferring data",
+			live: live,
+			config: `
+organization: example
+userRemovalPolicy: delete
+dataTransfer:
+  fallbackOwner: jane@example.com
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"delete user bob@example.com",
+			},
+		},
+		{
+			name: "suspend",
+			live: live,
+			config: `
+organization: example
+userRemovalPolicy: suspend
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"suspend user bob@example.com",
+			},
+		},
+		{
+			name: "suspend, then delete after a grace period",
+			live: live,
+			config: `
+organization: example
+userRemovalPolicy:
+  suspendThenDeleteAfter: 30d
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"suspend user bob@example.com",
+			},
+		},
+		{
+			name: "move to an org unit and strip licenses",
+			live: live,
+			config: `
+organization: example
+userRemovalPolicy:
+  moveToOrgUnit: /Offboarded
+orgUnits:
+  - name: Offboarded
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: []string{
+				"move user bob@example.com",
+				"delete license bob@example.com/GoogleWorkspaceBusinessStarter",
+			},
+		},
+	})
+}
+
+func TestPlanUserRemovalAfterGracePeriod(t *testing.T) {
+	clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+
+	syncConfig(t, clients, loadConfig(t, `
+organization: example
+users:
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+`))
+
+	cfg := loadConfig(t, `
+organization: example
+userRemovalPolicy:
+  suspendThenDeleteAfter: 30d
+`)
+
+	plan := planConfig(t, clients, cfg)
+	applyPlan(t, clients, plan)
+
+	if actions := describeActions(planConfig(t, clients, cfg)); len(actions) > 0 {
+		t.Fatalf("Expected no actions during the grace period, but got %q", actions)
+	}
+
+	// pretend the user was suspended long ago
+	for i := range plan.Actions {
+		plan.Actions[i].DeleteAfter = time.Now().AddDate(0, 0, -2).Format(deleteAfterLayout)
+	}
+	applyPlan(t, clients, plan)
+
+	expected := []string{"delete user bob@example.com"}
+	if actions := describeActions(planConfig(t, clients, cfg)); !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("Expected actions\n%q\nbut got\n%q", expected, actions)
+	}
+}
+
+func TestPlanUserUnsuspension(t *testing.T) {
+	const password = "i-am-not-secure-at-all"
+
+	ctx := context.Background()
+	ws := fake.NewWorkspace(testOrganization, config.AllLicenses)
+	clients := fakeClients(ws)
+
+	cfg := loadConfig(t, `
+organization: example
+userRemovalPolicy:
+  suspendThenDeleteAfter: 30d
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    password: `+password+`
+`)
+	syncConfig(t, clients, cfg)
+
+	// users suspended by someone else are left alone
+	if _, err := ws.UpdateUser(ctx, &directoryv1.User{PrimaryEmail: "jane@example.com"}, &directoryv1.User{Suspended: true}); err != nil {
+		t.Fatalf("Failed to suspend user: %v", err)
+	}
+
+	syncConfig(t, clients, loadConfig(t, `
+organization: example
+userRemovalPolicy:
+  suspendThenDeleteAfter: 30d
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`))
+
+	assertBob := func(suspended bool) {
+		t.Helper()
+
+		bob, err := ws.GetUser(ctx, "bob@example.com")
+		if err != nil {
+			t.Fatalf("Failed to get user: %v", err)
+		}
+
+		schema := config.GetUserSchema(bob)
+		if schema == nil || !config.PasswordMatches(password, schema.PasswordHash) {
+			t.Fatalf("Expected the password hash to be kept, but got %+v", schema)
+		}
+
+		if bob.Suspended != suspended || (schema.DeleteAfter != "") != suspended {
+			t.Fatalf("Expected suspended to be %v, but got %v with deleteAfter %q", suspended, bob.Suspended, schema.DeleteAfter)
+		}
+	}
+
+	assertBob(true)
+
+	plan := planConfig(t, clients, cfg)
+
+	expected := []string{"unsuspend user bob@example.com"}
+	if actions := describeActions(plan); !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("Expected actions\n%q\nbut got\n%q", expected, actions)
+	}
+
+	applyPlan(t, clients, plan)
+	assertBob(false)
+
+	if actions := describeActions(planConfig(t, clients, cfg)); len(actions) > 0 {
+		t.Fatalf("Expected no actions after unsuspending, but got %q", actions)
+	}
+}
+
+func TestPlanUserRemovalDataTransfer(t *testing.T) {
+	const live = `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Max
+    familyName: Mustermann
+    primaryEmail: max@example.com
+  - givenName: Bob
+    familyName: Smith
+    primaryEmail: bob@example.com
+    employeeInfo:
+      managerEmail: max@example.com
+`
+
+	testcases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "transfer to the manager",
+			config: `
+organization: example
+dataTransfer:
+  fallbackOwner: jane@example.com
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+  - givenName: Max
+    familyName: Mustermann
+    primaryEmail: max@example.com
+`,
+			expected: "max@example.com",
+		},
+		{
+			name: "transfer to the fallback owner if the manager leaves",
+			config: `
+organization: example
+dataTransfer:
+  fallbackOwner: jane@example.com
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+			expected: "jane@example.com",
+		},
+		{
+			name: "keep users without a recipient",
+			config: `
+organization: example
+dataTransfer:
+  fallbackOwner: nobody@example.com
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := fakeClients(fake.NewWorkspace(testOrganization, config.AllLicenses))
+			syncConfig(t, clients, loadConfig(t, live))
+
+			plan := planConfig(t, clients, loadConfig(t, tc.config))
+
+			recipient := ""
+			deleted := false
+
+			for _, action := range plan.Actions {
+				if action.Resource == UserResource && action.Name == "bob@example.com" {
+					deleted = true
+					recipient = action.TransferTo
+				}
+			}
+
+			if transfers := plan.TransfersData(); transfers != (tc.expected != "") {
+				t.Fatalf("Expected TransfersData to be %v, but got %v", tc.expected != "", transfers)
+			}
+
+			if tc.expected == "" {
+				if deleted {
+					t.Fatal("Expected bob@example.com to be kept, but they are deleted")
+				}
+				return
+			}
+
+			if recipient != tc.expected {
+				t.Fatalf("Expected data to be transferred to %q, but got %q", tc.expected, recipient)
+			}
+
+			applyPlan(t, clients, plan)
+		})
+	}
+}
+
+func TestPlanUsersMixedCaseLiveEmails(t *testing.T) {
+	ws := fake.NewWorkspace(testOrganization, config.AllLicenses)
+	clients := fakeClients(ws)
+
+	// GSuite lowercases emails itself, but older accounts can still have
+	// mixed-case addresses
+	_, err := ws.CreateUser(context.Background(), &directoryv1.User{
+		PrimaryEmail: "Jane@Example.com",
+		Name:         &directoryv1.UserName{GivenName: "Jane", FamilyName: "Doe"},
+		OrgUnitPath:  "/",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	cfg := loadConfig(t, `
+organization: example
+users:
+  - givenName: Jane
+    familyName: Doe
+    primaryEmail: jane@example.com
+groups:
+  - name: Team
+    email: team@example.com
+    members:
+      - email: JANE@example.com
+`)
+
+	if emails := cfg.MixedCaseEmails(); !reflect.DeepEqual(emails, []string{"JANE@example.com"}) {
+		t.Fatalf("Expected JANE@example.com to be reported as mixed case, but got %v", emails)
+	}
+
+	expected := []string{
+		"create group team@example.com",
+		"create member team@example.com/jane@example.com",
+	}
+
+	if actions := describeActions(planConfig(t, clients, cfg)); !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("Expected actions\n%q\nbut got\n%q", expected, actions)
+	}
+}